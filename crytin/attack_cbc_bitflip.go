@@ -0,0 +1,147 @@
+package crytin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//CBC bitflipping
+//
+// cb[i-1] XOR dec(key, cb[i]) = pb[i]
+//
+// Flip a bit of cb[i-1] and the same bit of pb[i] flips:
+//   cb'[i-1] = cb[i-1] XOR known XOR want  =>  pb'[i] = want
+// The price is pb[i-1], dec(key, cb'[i-1]) is garbage ("scrambled").
+// For the first block the IV is the previous block, nothing gets scrambled.
+
+// ErrFlipSpan : flip does not fit in one plain text block
+var ErrFlipSpan = errors.New("crytin: flip must stay within one block")
+
+// FlipCBC : tampers cb (or iv) so the plain text at offset decrypts to want
+// instead of known. known and want must be the same length and lie in one block.
+// returns tampered copies and the index of the block that gets scrambled,
+// -1 when only the iv had to change
+func FlipCBC(cb, iv []byte, offset int, known, want []byte, ks int) (tcb, tiv []byte, scrambled int, err error) {
+	if ks <= 0 {
+		return nil, nil, 0, ErrBlockSize
+	}
+	if len(known) != len(want) {
+		return nil, nil, 0, fmt.Errorf("crytin: known and want differ in length (%d, %d)", len(known), len(want))
+	}
+	if offset < 0 || offset+len(known) > len(cb) {
+		return nil, nil, 0, fmt.Errorf("crytin: offset %d out of range", offset)
+	}
+	block := offset / ks
+	if len(known) > 0 && (offset+len(known)-1)/ks != block {
+		return nil, nil, 0, ErrFlipSpan
+	}
+	if block == 0 && len(iv) != ks {
+		// the first block flips through the iv
+		return nil, nil, 0, ErrBlockSize
+	}
+
+	tcb = append([]byte{}, cb...)
+	tiv = append([]byte{}, iv...)
+
+	// previous block: the iv for block 0
+	prev, pos := tcb, offset-ks
+	if block == 0 {
+		prev, pos = tiv, offset
+	}
+	for i := range known {
+		prev[pos+i] ^= known[i] ^ want[i]
+	}
+	return tcb, tiv, block - 1, nil
+}
+
+//Comment string oracle (challenge 16)
+//
+// comment1=cooking%20MCs;userdata=<user data>;comment2=%20like%20a%20pound%20of%20bacon
+//
+// ';' and '=' in the user data are quoted out, the attacker wants
+// ";admin=true;" in the decrypted string.
+
+const (
+	commentPrefix = "comment1=cooking%20MCs;userdata="
+	commentSuffix = ";comment2=%20like%20a%20pound%20of%20bacon"
+
+	// CommentPrefixLen : offset of the user data in a comment string
+	CommentPrefixLen = len(commentPrefix)
+)
+
+// QuoteUserData : quotes ';' and '=' out of user data, ; => %3B and = => %3D
+func QuoteUserData(userdata []byte) []byte {
+	q := bytes.Replace(userdata, []byte(";"), []byte("%3B"), -1)
+	return bytes.Replace(q, []byte("="), []byte("%3D"), -1)
+}
+
+// CommentString : prefix || quoted user data || suffix
+func CommentString(userdata []byte) []byte {
+	s := append([]byte(commentPrefix), QuoteUserData(userdata)...)
+	return append(s, commentSuffix...)
+}
+
+// HasAdmin : does the decrypted comment string hold ";admin=true;"
+func HasAdmin(pb []byte) bool {
+	return bytes.Contains(pb, []byte(";admin=true;"))
+}
+
+// CBCCommentOracle : AES-CBC encrypted comment strings under a secret key
+type CBCCommentOracle struct {
+	key []byte
+	iv  []byte
+}
+
+// NewCBCCommentOracle : comment oracle with key and iv read from rnd (nil for crypto/rand)
+func NewCBCCommentOracle(rnd io.Reader) (*CBCCommentOracle, error) {
+	key, err := RandomKey(rnd)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := RandomIV(rnd)
+	if err != nil {
+		return nil, err
+	}
+	return &CBCCommentOracle{key: key, iv: iv}, nil
+}
+
+// IV : the iv cipher texts are made with
+func (o *CBCCommentOracle) IV() []byte {
+	return append([]byte{}, o.iv...)
+}
+
+// Encrypt : AES-CBC(comment string of userdata)
+func (o *CBCCommentOracle) Encrypt(userdata []byte) ([]byte, error) {
+	pb := CommentString(userdata)
+	PKCS7PadStrict(&pb, 16)
+	return EncryptAesCbcRaw(pb, o.key, o.iv)
+}
+
+// Decrypt : plain comment string of cb under iv, padding strictly checked
+func (o *CBCCommentOracle) Decrypt(cb, iv []byte) ([]byte, error) {
+	pb, err := DecryptAesCbcRaw(cb, o.key, iv)
+	if err != nil {
+		return nil, err
+	}
+	if err := PKCS7Unpad(&pb, 16); err != nil {
+		return nil, err
+	}
+	return pb, nil
+}
+
+// ValidPadding : padding check of the decryption, the oracle is a PaddingOracle
+func (o *CBCCommentOracle) ValidPadding(cb, iv []byte) bool {
+	_, err := o.Decrypt(cb, iv)
+	return err == nil
+}
+
+// IsAdmin : does cb under iv decrypt to a comment string with ";admin=true;"
+func (o *CBCCommentOracle) IsAdmin(cb, iv []byte) (bool, error) {
+	pb, err := o.Decrypt(cb, iv)
+	if err != nil {
+		return false, err
+	}
+	return HasAdmin(pb), nil
+}
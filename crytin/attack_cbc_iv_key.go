@@ -0,0 +1,100 @@
+package crytin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+//Recover the key from CBC with IV=Key
+//
+// Using the key as the IV saves sending an IV, and gives the key away
+// to anyone who sees a decryption error that echoes the plain text.
+//
+// Send C1 || 0 || C1 (|| the rest, it does not matter):
+//   P'1 = dec(C1) XOR IV  = dec(C1) XOR key
+//   P'2 = dec(0)  XOR C1  (garbage)
+//   P'3 = dec(C1) XOR 0   = dec(C1)
+// P'1 XOR P'3 = key
+
+// ASCIIError : decryption holds high-ASCII bytes
+// the error message echoes the plain text, which is the leak
+type ASCIIError struct {
+	Plaintext []byte
+}
+
+func (e *ASCIIError) Error() string {
+	return fmt.Sprintf("crytin: invalid ASCII in plain text %s", ToHex(e.Plaintext))
+}
+
+// DecryptOracle : decrypts cb and only returns an error
+type DecryptOracle interface {
+	Decrypt(cb []byte) error
+}
+
+// ErrNoLeak : the oracle did not leak the plain text
+var ErrNoLeak = errors.New("crytin: oracle did not leak the plain text")
+
+// AttackCBCKeyAsIV : recovers the key of a CBC oracle that uses the key as IV
+// cb is any cipher text of at least 3 blocks made by the oracle
+func AttackCBCKeyAsIV(oracle DecryptOracle, cb []byte, ks int) ([]byte, error) {
+	if len(cb) < 3*ks || len(cb)%ks != 0 {
+		return nil, ErrBlockSize
+	}
+	c1 := cb[:ks]
+	forged := make([]byte, 0, len(cb))
+	forged = append(forged, c1...)
+	forged = append(forged, make([]byte, ks)...)
+	forged = append(forged, c1...)
+	forged = append(forged, cb[3*ks:]...)
+
+	var leak *ASCIIError
+	if err := oracle.Decrypt(forged); !errors.As(err, &leak) {
+		return nil, ErrNoLeak
+	}
+	return XOR(leak.Plaintext[:ks], leak.Plaintext[2*ks:3*ks]), nil
+}
+
+// CBCKeyAsIVOracle : comment string oracle (challenge 16) that uses the key as IV
+// it goes around CheckCBCIV on purpose
+type CBCKeyAsIVOracle struct {
+	key []byte
+}
+
+// NewCBCKeyAsIVOracle : IV=key oracle with a key read from rnd (nil for crypto/rand)
+func NewCBCKeyAsIVOracle(rnd io.Reader) (*CBCKeyAsIVOracle, error) {
+	key, err := RandomKey(rnd)
+	if err != nil {
+		return nil, err
+	}
+	return &CBCKeyAsIVOracle{key: key}, nil
+}
+
+// Encrypt : AES-CBC(comment string of userdata, key, iv=key)
+func (o *CBCKeyAsIVOracle) Encrypt(userdata []byte) ([]byte, error) {
+	c, err := newAesCipher(o.key)
+	if err != nil {
+		return nil, err
+	}
+	pb := CommentString(userdata)
+	PKCS7PadStrict(&pb, uint(c.BlockSize()))
+	return cbcEncrypt(c, pb, o.key), nil
+}
+
+// Decrypt : decrypts cb, high-ASCII plain text is an *ASCIIError
+func (o *CBCKeyAsIVOracle) Decrypt(cb []byte) error {
+	c, err := newAesCipher(o.key)
+	if err != nil {
+		return err
+	}
+	if len(cb) == 0 || len(cb)%c.BlockSize() != 0 {
+		return ErrBlockSize
+	}
+	pb := cbcDecrypt(c, cb, o.key)
+	for _, b := range pb {
+		if b > 127 {
+			return &ASCIIError{Plaintext: pb}
+		}
+	}
+	return PKCS7Unpad(&pb, uint(c.BlockSize()))
+}
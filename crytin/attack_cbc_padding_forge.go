@@ -0,0 +1,47 @@
+package crytin
+
+import (
+	"io"
+)
+
+//CBC-R : encrypting with a padding oracle
+//
+// The padding oracle attack gives I = dec(key, C) for any block C we pick,
+// and pb(i) = I XOR cb(i-1) where cb(i-1) is ours to choose. So work
+// backwards from a random last block:
+//
+//   C[n]   = random
+//   C[n-1] = dec(key, C[n]) XOR P[n]
+//   ...
+//   IV     = dec(key, C[1]) XOR P[1]
+//
+// No key needed, one padding oracle attack per block.
+
+// ForgeCBCPaddingOracle : iv and cipher text that decrypt to pb under the
+// oracle's key, pb gets PKCS7 padded
+// the random last block is read from rnd (nil for crypto/rand)
+func ForgeCBCPaddingOracle(oracle PaddingOracle, pb []byte, ks int, rnd io.Reader) (cb, iv []byte, err error) {
+	pt := append([]byte{}, pb...)
+	PKCS7PadStrict(&pt, uint(ks))
+	n := len(pt) / ks
+
+	// blocks[0] is the iv, blocks[n] the random last block
+	blocks := make([][]byte, n+1)
+	if blocks[n], err = RandomBytes(rnd, ks); err != nil {
+		return nil, nil, err
+	}
+
+	for i := n; i > 0; i-- {
+		inter, err := paddingOracleIntermediate(oracle, blocks[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks[i-1] = XOR(inter, pt[(i-1)*ks:i*ks])
+	}
+
+	cb = make([]byte, 0, len(pt))
+	for _, b := range blocks[1:] {
+		cb = append(cb, b...)
+	}
+	return cb, blocks[0], nil
+}
@@ -0,0 +1,134 @@
+package crytin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+//CBC padding oracle
+//
+// The oracle decrypts any (iv, cipher text) and only tells whether the
+// padding was valid. That is enough to decrypt everything.
+
+// NOTES:
+//
+// cb(i-1) XOR dec(key,cb(i)) = pb(i)
+//
+// Send cb(i) alone with a forged previous block (the IV) F:
+//   F XOR dec(key,cb(i)) = tb
+//
+// Walk F[ks-1] over 0..255 till the padding is valid, then tb[ks-1] = 0x01
+//   I[ks-1] = dec(key,cb(i))[ks-1] = F[ks-1] XOR 0x01
+//
+// Set F[ks-1] = I[ks-1] XOR 0x02 and walk F[ks-2] till valid, tb = ..02 02
+//   I[ks-2] = F[ks-2] XOR 0x02
+//
+// ... and so on till I (the "intermediate" block) is known, then
+//   pb(i) = I XOR cb(i-1)        (the real IV for the first block)
+//
+// False positive: on the last byte, tb may end in ..02 02 (or 03 03 03)
+// when F[ks-1] happens to make it 02. Flip F[ks-2] and ask again, a real
+// 0x01 stays valid whatever sits in front of it.
+
+// PaddingOracle : tells whether cb decrypts under iv to valid padding
+type PaddingOracle interface {
+	ValidPadding(cb, iv []byte) bool
+}
+
+// ErrPaddingOracle : no byte value gave valid padding
+var ErrPaddingOracle = errors.New("crytin: padding oracle gave no valid padding")
+
+// paddingOracleIntermediate : dec(key, block) using only the oracle
+func paddingOracleIntermediate(oracle PaddingOracle, block []byte) ([]byte, error) {
+	ks := len(block)
+	inter := make([]byte, ks)
+	forged := make([]byte, ks)
+
+	for pos := ks - 1; pos >= 0; pos-- {
+		pad := byte(ks - pos)
+		for i := pos + 1; i < ks; i++ {
+			forged[i] = inter[i] ^ pad
+		}
+
+		found := false
+		for g := 0; g < 256 && !found; g++ {
+			forged[pos] = byte(g)
+			if !oracle.ValidPadding(block, forged) {
+				continue
+			}
+			if pos == ks-1 && ks > 1 {
+				// rule out ..02 02 and friends
+				forged[pos-1] ^= 0xff
+				valid := oracle.ValidPadding(block, forged)
+				forged[pos-1] ^= 0xff
+				if !valid {
+					continue
+				}
+			}
+			inter[pos] = byte(g) ^ pad
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: byte %d", ErrPaddingOracle, pos)
+		}
+	}
+	return inter, nil
+}
+
+// AttackCBCPaddingOracle : decrypts cb (with its iv) using only a padding oracle
+// recovers every block including the first, returns the unpadded plain text
+func AttackCBCPaddingOracle(oracle PaddingOracle, cb, iv []byte, ks int, verbose bool) ([]byte, error) {
+	if len(cb) == 0 || len(cb)%ks != 0 || len(iv) != ks {
+		return nil, ErrBlockSize
+	}
+
+	pb := make([]byte, 0, len(cb))
+	prev := iv
+	for i := 0; i < len(cb); i += ks {
+		inter, err := paddingOracleIntermediate(oracle, cb[i:i+ks])
+		if err != nil {
+			return nil, err
+		}
+		pb = append(pb, XOR(inter, prev)...)
+		prev = cb[i : i+ks]
+		if verbose {
+			fmt.Printf("\n block %d : %s", i/ks, ToSafeString(pb[i:]))
+		}
+	}
+
+	if err := PKCS7Unpad(&pb, uint(ks)); err != nil {
+		return nil, err
+	}
+	return pb, nil
+}
+
+// CBCPaddingOracle : AES-CBC with a secret key that leaks only padding validity
+type CBCPaddingOracle struct {
+	key []byte
+}
+
+// NewCBCPaddingOracle : padding oracle with a key read from rnd (nil for crypto/rand)
+func NewCBCPaddingOracle(rnd io.Reader) (*CBCPaddingOracle, error) {
+	key, err := RandomKey(rnd)
+	if err != nil {
+		return nil, err
+	}
+	return &CBCPaddingOracle{key: key}, nil
+}
+
+// Encrypt : AES-CBC(PKCS7(pb), key, iv)
+func (o *CBCPaddingOracle) Encrypt(pb, iv []byte) ([]byte, error) {
+	pb = append([]byte{}, pb...)
+	PKCS7PadStrict(&pb, 16)
+	return EncryptAesCbcRaw(pb, o.key, iv)
+}
+
+// ValidPadding : decrypts and strictly validates the PKCS7 padding
+func (o *CBCPaddingOracle) ValidPadding(cb, iv []byte) bool {
+	pb, err := DecryptAesCbcRaw(cb, o.key, iv)
+	if err != nil {
+		return false
+	}
+	return PKCS7Unpad(&pb, 16) == nil
+}
@@ -0,0 +1,68 @@
+package crytin
+
+import (
+	"fmt"
+	"io"
+)
+
+//CTR bitflipping
+//
+// cb = pb XOR key stream, flip a cipher text bit and the same plain text bit
+// flips, nothing else changes:
+//   cb'[i] = cb[i] XOR known[i] XOR want[i]  =>  pb'[i] = want[i]
+// Unlike CBC there is no sacrificial block, and no block boundaries to respect.
+// Works the same for any XOR stream cipher (CTR, OFB, MT19937 stream, ...).
+
+// FlipXOR : tampers a copy of an XOR malleable cipher text so the plain text
+// at offset decrypts to want instead of known
+func FlipXOR(cb []byte, offset int, known, want []byte) ([]byte, error) {
+	if len(known) != len(want) {
+		return nil, fmt.Errorf("crytin: known and want differ in length (%d, %d)", len(known), len(want))
+	}
+	if offset < 0 || offset+len(known) > len(cb) {
+		return nil, fmt.Errorf("crytin: offset %d out of range", offset)
+	}
+	tcb := append([]byte{}, cb...)
+	for i := range known {
+		tcb[offset+i] ^= known[i] ^ want[i]
+	}
+	return tcb, nil
+}
+
+// CTRCommentOracle : AES-CTR encrypted comment strings (challenge 26)
+type CTRCommentOracle struct {
+	key   []byte
+	nonce []byte
+}
+
+// NewCTRCommentOracle : comment oracle with key and nonce read from rnd (nil for crypto/rand)
+func NewCTRCommentOracle(rnd io.Reader) (*CTRCommentOracle, error) {
+	key, err := RandomKey(rnd)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := RandomNonce(rnd)
+	if err != nil {
+		return nil, err
+	}
+	return &CTRCommentOracle{key: key, nonce: nonce}, nil
+}
+
+// Encrypt : AES-CTR(comment string of userdata)
+func (o *CTRCommentOracle) Encrypt(userdata []byte) ([]byte, error) {
+	return EncryptAesCtr(CommentString(userdata), o.key, o.nonce)
+}
+
+// Decrypt : plain comment string of cb
+func (o *CTRCommentOracle) Decrypt(cb []byte) ([]byte, error) {
+	return DecryptAesCtr(cb, o.key, o.nonce)
+}
+
+// IsAdmin : does cb decrypt to a comment string with ";admin=true;"
+func (o *CTRCommentOracle) IsAdmin(cb []byte) (bool, error) {
+	pb, err := o.Decrypt(cb)
+	if err != nil {
+		return false, err
+	}
+	return HasAdmin(pb), nil
+}
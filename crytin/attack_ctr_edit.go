@@ -0,0 +1,51 @@
+package crytin
+
+import "io"
+
+//Break "random access read/write" AES CTR
+//
+// An edit API re-encrypts new text at any offset under the same key stream.
+// Edit the whole cipher text with zeros (or the cipher text itself):
+//   edit(cb, 0, zeros) = key stream
+//   pb = cb XOR key stream
+// or in one go:
+//   edit(cb, 0, cb) = cb XOR key stream = pb
+
+// EditOracle : re-encrypts newtext into cb at offset, key stays secret
+type EditOracle interface {
+	Edit(cb []byte, offset int, newtext []byte) ([]byte, error)
+}
+
+// AttackCTREdit : recovers the plain text of cb using only the edit oracle
+func AttackCTREdit(oracle EditOracle, cb []byte) ([]byte, error) {
+	return oracle.Edit(cb, 0, cb)
+}
+
+// CTREditOracle : AES-CTR "disk" with a secret key and nonce
+type CTREditOracle struct {
+	key   []byte
+	nonce []byte
+}
+
+// NewCTREditOracle : edit oracle with key and nonce read from rnd (nil for crypto/rand)
+func NewCTREditOracle(rnd io.Reader) (*CTREditOracle, error) {
+	key, err := RandomKey(rnd)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := RandomNonce(rnd)
+	if err != nil {
+		return nil, err
+	}
+	return &CTREditOracle{key: key, nonce: nonce}, nil
+}
+
+// Encrypt : AES-CTR(pb)
+func (o *CTREditOracle) Encrypt(pb []byte) ([]byte, error) {
+	return EncryptAesCtr(pb, o.key, o.nonce)
+}
+
+// Edit : EditAesCtr under the secret key
+func (o *CTREditOracle) Edit(cb []byte, offset int, newtext []byte) ([]byte, error) {
+	return EditAesCtr(cb, o.key, o.nonce, offset, newtext)
+}
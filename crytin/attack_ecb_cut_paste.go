@@ -0,0 +1,176 @@
+package crytin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+//ECB cut-and-paste
+//
+// The oracle wraps user input in a known template and encrypts it with ECB:
+//
+//   AES-ECB-Encrypt(prefix || user-input || suffix, random-key)
+//
+// Every cipher block depends only on its own plain block, so any plain block
+// the oracle can be tricked into producing at a block boundary can be cut out
+// of its cipher text and pasted into a forged cipher text.
+
+// NOTES: Padded oracle plain text for a user-input length L
+//
+// email=AAAAAAAAAA|AAA&uid=10&role=|user............
+// ^prefix fixed    ^input free       ^suffix fixed ^pad fixed
+//
+// A target block T can be produced by block k for input length L when
+//   - every prefix/suffix/pad byte falling in block k already matches T
+//   - every input byte falling in block k can be set to T (not filtered)
+// Input bytes outside block k are free, they are filled with a filler byte.
+//
+// Metacharacters (&, =) can never come from the input, so a target block
+// containing them must line up with the template bytes that hold them.
+// Sliding L moves the suffix (and the pad) across the block boundaries
+// until such an alignment is found.
+
+// OracleTemplate : AES-ECB-Encrypt(prefix || user-input || suffix, random-key)
+type OracleTemplate interface {
+	// Encrypt : encrypts the user input wrapped in the template
+	Encrypt(input []byte) (cb []byte, err error)
+}
+
+// CutPasteTemplate : known layout of the oracle plain text
+type CutPasteTemplate struct {
+	Prefix    []byte // bytes before the user input
+	Suffix    []byte // bytes after the user input
+	Forbidden []byte // bytes the oracle eats, quotes or rejects
+	BlockSize int
+}
+
+// CutPastePiece : one oracle call of the plan
+// encrypting Input yields the wanted target block at cipher block Block
+type CutPastePiece struct {
+	Input []byte
+	Block int
+}
+
+// Errors returned by PlanCutAndPaste
+var (
+	// ErrNoAlignment : a target block can not be produced by any input length
+	ErrNoAlignment = errors.New("crytin: no input aligns the target block")
+	// ErrEmptyTarget : nothing to forge
+	ErrEmptyTarget = errors.New("crytin: empty cut-and-paste target")
+)
+
+const cutPasteFiller = byte('A')
+
+// padded : strictly PKCS7 padded copy of b, always a whole number of blocks
+func padded(b []byte, ks int) []byte {
+	pb := append([]byte{}, b...)
+	PKCS7PadStrict(&pb, uint(ks))
+	return pb
+}
+
+// allowed : can the input carry byte b
+func (tpl CutPasteTemplate) allowed(b byte) bool {
+	return bytes.IndexByte(tpl.Forbidden, b) < 0
+}
+
+// alignBlock : input of length inputLen that makes block k equal to target
+// returns nil if the template bytes in block k do not match
+func (tpl CutPasteTemplate) alignBlock(target []byte, inputLen, k int) []byte {
+	ks := tpl.BlockSize
+	pre := len(tpl.Prefix)
+
+	// the fixed part the oracle will actually encrypt
+	input := bytes.Repeat([]byte{cutPasteFiller}, inputLen)
+	full := padded(append(append(append([]byte{}, tpl.Prefix...), input...), tpl.Suffix...), ks)
+	if (k+1)*ks > len(full) {
+		return nil
+	}
+
+	for i := 0; i < ks; i++ {
+		pos := k*ks + i
+		switch {
+		case pos >= pre && pos < pre+inputLen:
+			if !tpl.allowed(target[i]) {
+				return nil
+			}
+			input[pos-pre] = target[i]
+		case full[pos] != target[i]:
+			return nil
+		}
+	}
+
+	// recheck with the real input bytes in place
+	full = padded(append(append(append([]byte{}, tpl.Prefix...), input...), tpl.Suffix...), ks)
+	if len(full) < (k+1)*ks || !bytes.Equal(full[k*ks:(k+1)*ks], target) {
+		return nil
+	}
+	return input
+}
+
+// PlanCutAndPaste : works out one oracle input per target block
+// target is the plain text the forged cipher text must decrypt to,
+//   it is strictly PKCS7 padded (PKCS7PadStrict), as EncryptAesEcb pads.
+// Inputs are searched from short to long, the first alignment wins.
+func PlanCutAndPaste(tpl CutPasteTemplate, target []byte) ([]CutPastePiece, error) {
+	ks := tpl.BlockSize
+	if ks <= 0 {
+		return nil, errors.New("crytin: invalid block size")
+	}
+	if !tpl.allowed(cutPasteFiller) {
+		return nil, fmt.Errorf("crytin: filler %q is forbidden", cutPasteFiller)
+	}
+	if len(target) == 0 {
+		return nil, ErrEmptyTarget
+	}
+
+	pt := padded(target, ks)
+	// longest input worth trying: every block position at every shift
+	maxLen := len(pt) + len(tpl.Prefix) + len(tpl.Suffix) + 2*ks
+
+	plan := make([]CutPastePiece, 0, len(pt)/ks)
+	for j := 0; j < len(pt); j += ks {
+		block := pt[j : j+ks]
+		piece, found := CutPastePiece{}, false
+		for inputLen := 0; inputLen <= maxLen && !found; inputLen++ {
+			blocks := (len(tpl.Prefix) + inputLen + len(tpl.Suffix) + ks) / ks
+			for k := 0; k < blocks; k++ {
+				if input := tpl.alignBlock(block, inputLen, k); input != nil {
+					piece, found = CutPastePiece{Input: input, Block: k}, true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: block %d %q", ErrNoAlignment, j/ks, ToSafeString(block))
+		}
+		plan = append(plan, piece)
+	}
+	return plan, nil
+}
+
+// AttackECBCutAndPaste : forges the cipher text of target
+// using only the oracle and the ciphertexts it returns
+func AttackECBCutAndPaste(oracle OracleTemplate, tpl CutPasteTemplate, target []byte, verbose bool) ([]byte, error) {
+	ks := tpl.BlockSize
+	plan, err := PlanCutAndPaste(tpl, target)
+	if err != nil {
+		return nil, err
+	}
+
+	forged := make([]byte, 0, len(plan)*ks)
+	for i, piece := range plan {
+		cb, err := oracle.Encrypt(piece.Input)
+		if err != nil {
+			return nil, err
+		}
+		if (piece.Block+1)*ks > len(cb) {
+			return nil, fmt.Errorf("crytin: oracle output too short for block %d", piece.Block)
+		}
+		if verbose {
+			fmt.Printf("\n block %d <= cb[%d] of input %q", i, piece.Block, ToSafeString(piece.Input))
+		}
+		forged = append(forged, cb[piece.Block*ks:(piece.Block+1)*ks]...)
+	}
+	return forged, nil
+}
@@ -0,0 +1,29 @@
+package crytin
+
+import "io"
+
+//MD4 length extension
+//
+// AttackMDLengthExtension on MD4Func: the glue writes the bit length little
+// endian and the MAC holds four little endian words.
+
+// MD4MAC : secret prefix MAC, MD4(key || message)
+func MD4MAC(key, message []byte) []byte {
+	return MD4Func.MAC(key, message)
+}
+
+// ExtendMD4 : message || glue || extra and its MAC for a key of keyLen bytes
+func ExtendMD4(message, mac, extra []byte, keyLen int) (forged, forgedMAC []byte, err error) {
+	return MD4Func.Extend(message, mac, extra, keyLen)
+}
+
+// AttackMD4LengthExtension : appends extra to a MACed message, trying key
+// lengths 0..maxKeyLen till the oracle accepts
+func AttackMD4LengthExtension(oracle MACOracle, message, mac, extra []byte, maxKeyLen int) (forged, forgedMAC []byte, keyLen int, err error) {
+	return AttackMDLengthExtension(MD4Func, oracle, message, mac, extra, maxKeyLen)
+}
+
+// NewMD4MACOracle : MD4(key || message) oracle with a keyLen byte key read from rnd (nil for crypto/rand)
+func NewMD4MACOracle(rnd io.Reader, keyLen int) (*MDMACOracle, error) {
+	return NewMDMACOracle(MD4Func, rnd, keyLen)
+}
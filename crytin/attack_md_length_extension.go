@@ -0,0 +1,81 @@
+package crytin
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//Merkle-Damgard length extension
+//
+// secret prefix MAC: mac = H(key || message)
+//
+// mac is the state after hashing key || message || glue, where glue is the
+// padding for len(key)+len(message). Load mac as the state and hash on:
+//   H(key || message || glue || extra) without knowing the key
+// Only len(key) is needed for the glue, try them all against the verifier.
+
+// MACOracle : verifies MACs under a secret key
+type MACOracle interface {
+	Verify(message, mac []byte) bool
+}
+
+// ErrNoExtension : no key length gave a MAC the oracle accepts
+var ErrNoExtension = errors.New("crytin: no key length gave a valid extension")
+
+// AttackMDLengthExtension : appends extra to a message with a secret prefix
+// MAC under f, trying key lengths 0..maxKeyLen till the oracle accepts
+func AttackMDLengthExtension(f *MDFunc, oracle MACOracle, message, mac, extra []byte, maxKeyLen int) (forged, forgedMAC []byte, keyLen int, err error) {
+	for keyLen = 0; keyLen <= maxKeyLen; keyLen++ {
+		forged, forgedMAC, err = f.Extend(message, mac, extra, keyLen)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if oracle.Verify(forged, forgedMAC) {
+			return forged, forgedMAC, keyLen, nil
+		}
+	}
+	return nil, nil, 0, fmt.Errorf("%w: tried up to %d", ErrNoExtension, maxKeyLen)
+}
+
+// IsAdminMessage : does a ';' separated message hold the field admin=true
+func IsAdminMessage(message []byte) bool {
+	for _, field := range bytes.Split(message, []byte(";")) {
+		if string(field) == "admin=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// MDMACOracle : signs and verifies H(key || message) under a secret key
+type MDMACOracle struct {
+	f   *MDFunc
+	key []byte
+}
+
+// NewMDMACOracle : MAC oracle for f with a keyLen byte key read from rnd (nil for crypto/rand)
+func NewMDMACOracle(f *MDFunc, rnd io.Reader, keyLen int) (*MDMACOracle, error) {
+	key, err := RandomBytes(rnd, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	return &MDMACOracle{f: f, key: key}, nil
+}
+
+// Sign : MAC of message
+func (o *MDMACOracle) Sign(message []byte) []byte {
+	return o.f.MAC(o.key, message)
+}
+
+// Verify : is mac the MAC of message
+func (o *MDMACOracle) Verify(message, mac []byte) bool {
+	return subtle.ConstantTimeCompare(o.f.MAC(o.key, message), mac) == 1
+}
+
+// IsAdmin : valid MAC and an admin=true field
+func (o *MDMACOracle) IsAdmin(message, mac []byte) bool {
+	return o.Verify(message, mac) && IsAdminMessage(message)
+}
@@ -0,0 +1,269 @@
+package crytin
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Clone an MT19937 RNG from its output
+//
+// Each output is one state word put through Temper. Every tempering step
+// is invertible, so Untemper gives the state word back. 624 consecutive
+// outputs are 624 consecutive state words, which is all the state there is:
+//   x[k+624] = x[k+397] XOR twist(x[k], x[k+1])
+// holds for any window, so the clone does not need to know where the
+// generator was in its twist cycle.
+
+// ErrNotMT19937 : outputs do not come from a single MT19937
+var ErrNotMT19937 = errors.New("crytin: outputs do not match an MT19937 stream")
+
+// undoRightShiftXor : inverts y ^= y >> shift
+func undoRightShiftXor(y uint32, shift uint) uint32 {
+	x := y
+	for i := uint(0); i < 32; i += shift {
+		x = y ^ (x >> shift)
+	}
+	return x
+}
+
+// undoLeftShiftXorAnd : inverts y ^= (y << shift) & mask
+func undoLeftShiftXorAnd(y uint32, shift uint, mask uint32) uint32 {
+	x := y
+	for i := uint(0); i < 32; i += shift {
+		x = y ^ ((x << shift) & mask)
+	}
+	return x
+}
+
+// Untemper : state word behind an MT19937 output, Untemper(Temper(y)) = y
+func Untemper(y uint32) uint32 {
+	y = undoRightShiftXor(y, 18)
+	y = undoLeftShiftXorAnd(y, 15, 0xefc60000)
+	y = undoLeftShiftXorAnd(y, 7, 0x9d2c5680)
+	y = undoRightShiftXor(y, 11)
+	return y
+}
+
+// CloneMT19937 : generator in the same state as the one that produced outputs
+// needs at least 624 consecutive outputs, any more are checked against the
+// clone; the clone continues right after the last output
+func CloneMT19937(outputs []uint32) (*MT19937, error) {
+	if len(outputs) < mtN {
+		return nil, fmt.Errorf("crytin: need %d outputs to clone, got %d", mtN, len(outputs))
+	}
+	m := &MT19937{index: mtN}
+	for i := 0; i < mtN; i++ {
+		m.mt[i] = Untemper(outputs[i])
+	}
+	for _, v := range outputs[mtN:] {
+		if m.Uint32() != v {
+			return nil, ErrNotMT19937
+		}
+	}
+	return m, nil
+}
+
+//Partly observed outputs
+//
+// Services rarely leak whole words: outputs get truncated (rand() >> 16,
+// Intn, a few bytes of a token) or some are never seen. Temper, Untemper
+// and the twist are all linear over GF(2), so every output bit is an XOR of
+// state bits. Unknown output bits of the first 624 outputs become the
+// variables, the state words are affine in them, and every known bit of a
+// later output is one linear equation. Gaussian elimination solves them.
+//
+// Roughly: unknown bits in the first 624 outputs <= known bits after them,
+// and as x[k] only reaches x[k+623] and x[k+624], the observations should
+// run about two twist cycles (~1250 outputs) for every unknown bit to show up.
+
+// MTObservation : one partly observed output
+// Mask has the bits of Value that were observed, Mask 0 is a missed output
+type MTObservation struct {
+	Value uint32
+	Mask  uint32
+}
+
+// bitvec : affine GF(2) form, bit n (the last) is the constant
+type bitvec []uint64
+
+func (v bitvec) get(i int) bool { return v[i/64]>>(uint(i)%64)&1 == 1 }
+func (v bitvec) flip(i int)     { v[i/64] ^= 1 << (uint(i) % 64) }
+func (v bitvec) xor(w bitvec) {
+	for i := range v {
+		v[i] ^= w[i]
+	}
+}
+
+// symWord : 32 affine bits of a state word
+type symWord [32]bitvec
+
+func newSymWord(nw int) symWord {
+	var w symWord
+	for b := range w {
+		w[b] = make(bitvec, nw)
+	}
+	return w
+}
+
+// gf2System : incremental row echelon form over nvars variables
+type gf2System struct {
+	nvars  int
+	pivots []bitvec // pivots[c] has lowest variable c
+	rank   int
+}
+
+// add : reduces eq against the pivots and keeps it if new
+func (s *gf2System) add(eq bitvec) error {
+	for c := 0; c < s.nvars; c++ {
+		if !eq.get(c) {
+			continue
+		}
+		if s.pivots[c] == nil {
+			s.pivots[c] = eq
+			s.rank++
+			return nil
+		}
+		eq.xor(s.pivots[c])
+	}
+	if eq.get(s.nvars) {
+		return ErrNotMT19937 // 0 = 1
+	}
+	return nil
+}
+
+// solve : one solution, free variables are 0
+func (s *gf2System) solve() []bool {
+	x := make([]bool, s.nvars)
+	for c := s.nvars - 1; c >= 0; c-- {
+		row := s.pivots[c]
+		if row == nil {
+			continue
+		}
+		v := row.get(s.nvars)
+		for d := c + 1; d < s.nvars; d++ {
+			if x[d] && row.get(d) {
+				v = !v
+			}
+		}
+		x[c] = v
+	}
+	return x
+}
+
+// CloneMT19937Partial : clones from partly observed consecutive outputs
+// the clone is checked against every observation and continues after the last
+func CloneMT19937Partial(obs []MTObservation) (*MT19937, error) {
+	if len(obs) < mtN {
+		return nil, fmt.Errorf("crytin: need at least %d observations, got %d", mtN, len(obs))
+	}
+
+	// variables: unknown bits of the first 624 outputs
+	type unknown struct{ word, bit int }
+	vars := []unknown{}
+	for i := 0; i < mtN; i++ {
+		for b := 0; b < 32; b++ {
+			if obs[i].Mask>>uint(b)&1 == 0 {
+				vars = append(vars, unknown{i, b})
+			}
+		}
+	}
+	nvars := len(vars)
+	if nvars == 0 {
+		outputs := make([]uint32, len(obs))
+		for i, o := range obs {
+			outputs[i] = o.Value
+		}
+		return CloneMT19937(outputs)
+	}
+	nw := nvars/64 + 1
+
+	// linear maps as columns: out = Temper(state), state = Untemper(out)
+	var temperCol, untemperCol [32]uint32
+	for b := uint(0); b < 32; b++ {
+		temperCol[b] = Temper(1 << b)
+		untemperCol[b] = Untemper(1 << b)
+	}
+
+	// symbolic state of the first window
+	ring := make([]symWord, mtN)
+	vi := 0
+	for i := 0; i < mtN; i++ {
+		w := newSymWord(nw)
+		known := Untemper(obs[i].Value & obs[i].Mask)
+		for b := uint(0); b < 32; b++ {
+			if known>>b&1 == 1 {
+				w[b].flip(nvars)
+			}
+		}
+		for ; vi < nvars && vars[vi].word == i; vi++ {
+			col := untemperCol[vars[vi].bit]
+			for b := uint(0); b < 32; b++ {
+				if col>>b&1 == 1 {
+					w[b].flip(vi)
+				}
+			}
+		}
+		ring[i] = w
+	}
+
+	// roll the symbolic twist over the later observations
+	sys := &gf2System{nvars: nvars, pivots: make([]bitvec, nvars)}
+	for k := mtN; k < len(obs) && sys.rank < nvars; k++ {
+		x0, x1, xm := ring[k%mtN], ring[(k+1)%mtN], ring[(k+mtM)%mtN]
+		next := newSymWord(nw)
+		for b := 0; b < 32; b++ {
+			copy(next[b], xm[b])
+			switch {
+			case b == 30:
+				next[b].xor(x0[31]) // y bit 31 is the upper bit of x[k]
+			case b < 30:
+				next[b].xor(x1[b+1])
+			}
+			if mtMatrixA>>uint(b)&1 == 1 {
+				next[b].xor(x1[0])
+			}
+		}
+		ring[k%mtN] = next
+
+		o := obs[k]
+		for b := uint(0); b < 32; b++ {
+			if o.Mask>>b&1 == 0 {
+				continue
+			}
+			eq := make(bitvec, nw)
+			for j := uint(0); j < 32; j++ {
+				if temperCol[j]>>b&1 == 1 {
+					eq.xor(next[j])
+				}
+			}
+			if o.Value>>b&1 == 1 {
+				eq.flip(nvars)
+			}
+			if err := sys.add(eq); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// concrete outputs of the first window, then clone and check everything
+	x := sys.solve()
+	outputs := make([]uint32, mtN)
+	for i := range outputs {
+		outputs[i] = obs[i].Value & obs[i].Mask
+	}
+	for i, v := range vars {
+		if x[i] {
+			outputs[v.word] |= 1 << uint(v.bit)
+		}
+	}
+	m, err := CloneMT19937(outputs)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range obs[mtN:] {
+		if (m.Uint32()^o.Value)&o.Mask != 0 {
+			return nil, fmt.Errorf("%w (rank %d of %d unknown bits)", ErrNotMT19937, sys.rank, nvars)
+		}
+	}
+	return m, nil
+}
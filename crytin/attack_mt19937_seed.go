@@ -0,0 +1,67 @@
+package crytin
+
+import (
+	"bytes"
+	"math/rand"
+	"time"
+)
+
+//Crack an MT19937 seed
+//
+// Seeding with the time, rand.Seed(time.Now().Unix()), leaves only as many
+// seeds as there are seconds in the window the attacker has to guess, a few
+// thousand for "sometime in the last hour". Try them all.
+//
+// The same goes for math/rand: the key a test reads after seeding with the
+// time is fixed by the seed, and the seed by any random bytes that leak later.
+
+// BruteForceSeed : tries seeds from to down to from (newest first)
+// returns the first seed match accepts
+func BruteForceSeed(from, to int64, match func(seed int64) bool) (int64, bool) {
+	for seed := to; seed >= from; seed-- {
+		if match(seed) {
+			return seed, true
+		}
+	}
+	return 0, false
+}
+
+// RecoverMT19937Seed : unix time seed in [from, to] whose first MT19937 output is output
+func RecoverMT19937Seed(output uint32, from, to time.Time) (uint32, bool) {
+	seed, ok := BruteForceSeed(from.Unix(), to.Unix(), func(seed int64) bool {
+		return NewMT19937(uint32(seed)).Uint32() == output
+	})
+	return uint32(seed), ok
+}
+
+// MathRandBytes : n bytes of a math/rand source seeded with seed
+// after skipping offset bytes, as rand.Seed(seed) followed by rand.Read would give
+func MathRandBytes(seed int64, offset, n int) []byte {
+	b := make([]byte, offset+n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b[offset:]
+}
+
+// RecoverMathRandSeed : unix time seed in [from, to] of a math/rand source that
+// produced observed after offset bytes were read from it
+func RecoverMathRandSeed(observed []byte, offset int, from, to time.Time) (int64, bool) {
+	return BruteForceSeed(from.Unix(), to.Unix(), func(seed int64) bool {
+		return bytes.Equal(MathRandBytes(seed, offset, len(observed)), observed)
+	})
+}
+
+// SeededReaderBytes : n bytes of NewSeededReader(seed) after skipping offset bytes
+func SeededReaderBytes(seed uint32, offset, n int) []byte {
+	b := make([]byte, offset+n)
+	NewSeededReader(seed).Read(b)
+	return b[offset:]
+}
+
+// RecoverSeededReaderSeed : unix time seed in [from, to] of a NewSeededReader
+// that produced observed after offset bytes were read from it
+func RecoverSeededReaderSeed(observed []byte, offset int, from, to time.Time) (uint32, bool) {
+	seed, ok := BruteForceSeed(from.Unix(), to.Unix(), func(seed int64) bool {
+		return bytes.Equal(SeededReaderBytes(uint32(seed), offset, len(observed)), observed)
+	})
+	return uint32(seed), ok
+}
@@ -0,0 +1,29 @@
+package crytin
+
+import "io"
+
+//SHA-1 length extension
+//
+// AttackMDLengthExtension on SHA1Func: the glue writes the bit length big
+// endian and the MAC holds h0..h4.
+
+// SHA1MAC : secret prefix MAC, SHA1(key || message)
+func SHA1MAC(key, message []byte) []byte {
+	return SHA1Func.MAC(key, message)
+}
+
+// ExtendSHA1 : message || glue || extra and its MAC for a key of keyLen bytes
+func ExtendSHA1(message, mac, extra []byte, keyLen int) (forged, forgedMAC []byte, err error) {
+	return SHA1Func.Extend(message, mac, extra, keyLen)
+}
+
+// AttackSHA1LengthExtension : appends extra to a MACed message, trying key
+// lengths 0..maxKeyLen till the oracle accepts
+func AttackSHA1LengthExtension(oracle MACOracle, message, mac, extra []byte, maxKeyLen int) (forged, forgedMAC []byte, keyLen int, err error) {
+	return AttackMDLengthExtension(SHA1Func, oracle, message, mac, extra, maxKeyLen)
+}
+
+// NewSHA1MACOracle : SHA1(key || message) oracle with a keyLen byte key read from rnd (nil for crypto/rand)
+func NewSHA1MACOracle(rnd io.Reader, keyLen int) (*MDMACOracle, error) {
+	return NewMDMACOracle(SHA1Func, rnd, keyLen)
+}
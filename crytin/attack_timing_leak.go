@@ -0,0 +1,210 @@
+package crytin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+//Timing leak attack on an early exit compare
+//
+// A signature with the first i bytes right takes i delays to reject.
+// For byte i try all 256 values and keep the slowest, the right one costs
+// one more delay than the rest.
+//
+// Noise: time every candidate several times and compare medians (or
+// trimmed means), a single sample can be hit by a GC or the scheduler.
+// Pruning: after each round keep the slower half, so most of the samples go
+// to the few candidates that are still close.
+// Mistakes: when no candidate for byte i stands out, byte i-1 was wrong,
+// step back. The last byte needs no timing, the server says 200.
+
+// TimingOracle : times one signature check
+type TimingOracle interface {
+	Time(signature []byte) (elapsed time.Duration, valid bool, err error)
+}
+
+// ErrTimingAttack : ran out of retries without a valid signature
+var ErrTimingAttack = errors.New("crytin: timing attack found no valid signature")
+
+// HTTPTimingOracle : times GET URL?file=File&signature=<hex>
+type HTTPTimingOracle struct {
+	URL    string
+	File   string
+	Client *http.Client // nil for http.DefaultClient
+}
+
+// Time : round trip time of one request, valid on 200 OK
+func (o *HTTPTimingOracle) Time(signature []byte) (time.Duration, bool, error) {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	q := url.Values{"file": {o.File}, "signature": {ToHex(signature)}}
+
+	start := time.Now()
+	resp, err := client.Get(o.URL + "?" + q.Encode())
+	if err != nil {
+		return 0, false, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return time.Since(start), resp.StatusCode == http.StatusOK, nil
+}
+
+// Median : middle timing
+func Median(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	s := append([]time.Duration{}, ds...)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	if len(s)%2 == 0 {
+		return (s[len(s)/2-1] + s[len(s)/2]) / 2
+	}
+	return s[len(s)/2]
+}
+
+// TrimmedMean : mean of the middle half of the timings (interquartile mean)
+func TrimmedMean(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	s := append([]time.Duration{}, ds...)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	lo, hi := len(s)/4, len(s)-len(s)/4
+	var sum time.Duration
+	for _, d := range s[lo:hi] {
+		sum += d
+	}
+	return sum / time.Duration(hi-lo)
+}
+
+// TimingOptions : sampling for AttackTimingLeak
+type TimingOptions struct {
+	Samples int                                 // timings per candidate and round, 0 for 3
+	Stat    func([]time.Duration) time.Duration // nil for Median
+	Retries int                                 // steps back before giving up, 0 for 8
+	Verbose bool
+}
+
+// AttackTimingLeak : recovers a macSize byte signature one byte at a time
+func AttackTimingLeak(oracle TimingOracle, macSize int, opts TimingOptions) ([]byte, error) {
+	if opts.Samples <= 0 {
+		opts.Samples = 3
+	}
+	if opts.Stat == nil {
+		opts.Stat = Median
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = 8
+	}
+
+	sig := make([]byte, macSize)
+	var step time.Duration // estimated delay per matching byte
+	retries := 0
+	for i := 0; i < macSize; {
+		var ok bool
+		var err error
+		if i == macSize-1 {
+			ok, err = timingLastByte(oracle, sig)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return sig, nil
+			}
+		} else {
+			var b byte
+			var signal time.Duration
+			b, signal, err = timingByte(oracle, sig, i, opts)
+			if err != nil {
+				return nil, err
+			}
+			ok = i == 0 || signal > step/2
+			if ok {
+				sig[i] = b
+				if i == 0 {
+					step = signal
+				} else {
+					step = (step + signal) / 2
+				}
+				if opts.Verbose {
+					fmt.Printf("\n byte %d : %02x (+%v)", i, b, signal)
+				}
+				i++
+				continue
+			}
+		}
+
+		// nothing stands out: the byte before was wrong
+		if i == 0 || retries == opts.Retries {
+			return nil, ErrTimingAttack
+		}
+		retries++
+		i--
+		if opts.Verbose {
+			fmt.Printf("\n back to byte %d", i)
+		}
+	}
+	return sig, nil
+}
+
+// timingByte : slowest value for sig[i] and how much it stands out
+// from the typical candidate
+func timingByte(oracle TimingOracle, sig []byte, i int, opts TimingOptions) (byte, time.Duration, error) {
+	probe := append([]byte{}, sig...)
+	times := make([][]time.Duration, 256)
+	stats := make([]time.Duration, 256)
+	cand := make([]int, 256)
+	for c := range cand {
+		cand[c] = c
+	}
+
+	var base time.Duration
+	for round := 0; len(cand) > 1; round++ {
+		// interleave candidates so slow spells hit all of them
+		for s := 0; s < opts.Samples; s++ {
+			for _, c := range cand {
+				probe[i] = byte(c)
+				d, _, err := oracle.Time(probe)
+				if err != nil {
+					return 0, 0, err
+				}
+				times[c] = append(times[c], d)
+			}
+		}
+		for _, c := range cand {
+			stats[c] = opts.Stat(times[c])
+		}
+		sort.Slice(cand, func(a, b int) bool { return stats[cand[a]] > stats[cand[b]] })
+		if round == 0 {
+			base = stats[cand[len(cand)/2]]
+		}
+		cand = cand[:len(cand)/2]
+	}
+	return byte(cand[0]), stats[cand[0]] - base, nil
+}
+
+// timingLastByte : tries every value of the last byte, sig is completed when valid
+func timingLastByte(oracle TimingOracle, sig []byte) (bool, error) {
+	probe := append([]byte{}, sig...)
+	last := len(probe) - 1
+	for c := 0; c < 256; c++ {
+		probe[last] = byte(c)
+		_, valid, err := oracle.Time(probe)
+		if err != nil {
+			return false, err
+		}
+		if valid {
+			sig[last] = byte(c)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,53 @@
+package crytin
+
+import "crypto/subtle"
+
+// Constant time helpers
+//
+// Time must not depend on secret data: no early exit, no branch and no
+// memory index on a secret. Lengths are public, those may still branch.
+// DetectTimingLeak checks them against their leaky counterparts:
+//   bytes.Equal, InsecureCompare : exit at the first mismatch
+//   PKCS7Unpad                   : exits at the first bad padding byte
+//   table[i]                     : cache lines give i away
+
+// ConstantTimeEqual : a == b, time depends only on the lengths
+func ConstantTimeEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var v byte
+	for i := range a {
+		v |= a[i] ^ b[i]
+	}
+	return subtle.ConstantTimeByteEq(v, 0) == 1
+}
+
+// ConstantTimePKCS7Unpad : PKCS7Unpad that checks every byte of the last block
+// whatever the padding holds
+func ConstantTimePKCS7Unpad(pb *[]byte, blockSize uint) error {
+	b := *pb
+	bs := int(blockSize)
+	if len(b) == 0 || len(b)%bs != 0 {
+		return ErrBlockSize
+	}
+	n := int(b[len(b)-1])
+	good := subtle.ConstantTimeLessOrEq(1, n) & subtle.ConstantTimeLessOrEq(n, bs)
+	for i := 1; i <= bs; i++ {
+		inPad := subtle.ConstantTimeLessOrEq(i, n)
+		good &= subtle.ConstantTimeByteEq(b[len(b)-i], byte(n)) | (inPad ^ 1)
+	}
+	// no branch on good either, the caller sees the error anyway but
+	// the time to get there must not differ
+	*pb = b[:subtle.ConstantTimeSelect(good, len(b)-n, len(b))]
+	return [2]error{ErrInvalidPadding, nil}[good]
+}
+
+// ConstantTimeLookup : table[index], reading every entry
+func ConstantTimeLookup(table []byte, index int) byte {
+	var v byte
+	for i := range table {
+		v |= byte(-subtle.ConstantTimeEq(int32(i), int32(index))) & table[i]
+	}
+	return v
+}
@@ -0,0 +1,335 @@
+// Package cookie : structured k=v cookie / profile codec
+//
+//   foo=bar&baz=qux&zap=zazzle
+//
+// is parsed into an ordered set of pairs that converts to
+//
+//   {"foo":"bar","baz":"qux","zap":"zazzle"}
+//
+// The parser is strict so it can stand in for a real service parsing
+// attacker-supplied cookies: duplicate keys, pairs without '=' and
+// broken escapes are errors, not something to guess about.
+package cookie
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by Parse and Encode
+var (
+	ErrEmptyKey     = errors.New("cookie: empty key")
+	ErrMissingValue = errors.New("cookie: pair has no '='")
+	ErrDuplicateKey = errors.New("cookie: duplicate key")
+	ErrBadEscape    = errors.New("cookie: invalid escape")
+	ErrBadQuote     = errors.New("cookie: unterminated or misplaced quote")
+	ErrMetaChars    = errors.New("cookie: metacharacter in key or value")
+)
+
+// Pair : single key=value
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Cookie : ordered key=value pairs with unique keys
+type Cookie struct {
+	pairs []Pair
+}
+
+// New : cookie from pairs, fails on duplicate or empty keys
+func New(pairs ...Pair) (*Cookie, error) {
+	c := &Cookie{}
+	for _, p := range pairs {
+		if err := c.add(p.Key, p.Value); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Cookie) add(key, value string) error {
+	if key == "" {
+		return ErrEmptyKey
+	}
+	if _, ok := c.Get(key); ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateKey, key)
+	}
+	c.pairs = append(c.pairs, Pair{key, value})
+	return nil
+}
+
+// Get : value of key
+func (c *Cookie) Get(key string) (string, bool) {
+	for _, p := range c.pairs {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set : replaces the value of key or appends a new pair
+func (c *Cookie) Set(key, value string) error {
+	if key == "" {
+		return ErrEmptyKey
+	}
+	for i := range c.pairs {
+		if c.pairs[i].Key == key {
+			c.pairs[i].Value = value
+			return nil
+		}
+	}
+	c.pairs = append(c.pairs, Pair{key, value})
+	return nil
+}
+
+// Pairs : copy of the pairs in order
+func (c *Cookie) Pairs() []Pair {
+	return append([]Pair{}, c.pairs...)
+}
+
+// Len : number of pairs
+func (c *Cookie) Len() int {
+	return len(c.pairs)
+}
+
+//
+// Parsing
+//
+// cookie  = pair *( "&" pair )
+// pair    = key "=" value
+// key     = *( char / escape )
+// value   = quoted / *( char / escape )
+// quoted  = DQUOTE *( qchar / "\" any ) DQUOTE
+// escape  = "%" HEXDIG HEXDIG
+//
+// char excludes '&', '=', '%' and '"'; a bare '"' is only allowed
+// as the first and last byte of a quoted value.
+
+// Parse : strict k=v parsing
+func Parse(s string) (*Cookie, error) {
+	c := &Cookie{}
+	if s == "" {
+		return c, nil
+	}
+	for i := 0; ; {
+		key, n, err := scanPlain(s[i:], "=&")
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		if i >= len(s) || s[i] != '=' {
+			return nil, fmt.Errorf("%w: %q", ErrMissingValue, key)
+		}
+		i++ // '='
+
+		var value string
+		if i < len(s) && s[i] == '"' {
+			value, n, err = scanQuoted(s[i:])
+		} else {
+			value, n, err = scanPlain(s[i:], "&")
+		}
+		if err != nil {
+			return nil, err
+		}
+		i += n
+
+		if err := c.add(key, value); err != nil {
+			return nil, err
+		}
+		if i == len(s) {
+			return c, nil
+		}
+		if s[i] != '&' {
+			return nil, fmt.Errorf("%w: unexpected %q at %d", ErrMetaChars, s[i], i)
+		}
+		i++ // '&'
+	}
+}
+
+// scanPlain : reads until one of stop, decoding %XX escapes
+// returns decoded text and number of bytes consumed
+func scanPlain(s, stop string) (string, int, error) {
+	var b strings.Builder
+	i := 0
+	for ; i < len(s) && strings.IndexByte(stop, s[i]) < 0; i++ {
+		switch s[i] {
+		case '%':
+			if i+2 >= len(s) {
+				return "", 0, fmt.Errorf("%w: %q", ErrBadEscape, s[i:])
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", 0, fmt.Errorf("%w: %q", ErrBadEscape, s[i:i+3])
+			}
+			b.WriteByte(byte(v))
+			i += 2
+		case '"':
+			return "", 0, ErrBadQuote
+		case '=':
+			return "", 0, fmt.Errorf("%w: '=' in value", ErrMetaChars)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), i, nil
+}
+
+// scanQuoted : reads a "quoted" value with \ escapes
+// returns decoded text and number of bytes consumed including quotes
+func scanQuoted(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, ErrBadQuote
+			}
+			i++
+			b.WriteByte(s[i])
+		case '"':
+			if i+1 < len(s) && s[i+1] != '&' {
+				return "", 0, ErrBadQuote
+			}
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, ErrBadQuote
+}
+
+//
+// Encoding
+//
+
+// Policy : what the encoder does with metacharacters (& = % ")
+type Policy int
+
+const (
+	// Escape : percent-encode metacharacters, foo&bar => foo%26bar
+	Escape Policy = iota
+	// Quote : wrap values holding metacharacters in quotes, foo&bar => "foo&bar"
+	// keys are still escaped, quoting only applies to values
+	Quote
+	// Reject : refuse to encode metacharacters, like the challenge's profile_for
+	Reject
+)
+
+const metaChars = "&=%\""
+
+// HasMetaChars : does s hold any of & = % "
+func HasMetaChars(s string) bool {
+	return strings.ContainsAny(s, metaChars)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(metaChars, s[i]) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", s[i])
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Encode : k=v encoding of c under policy
+func (c *Cookie) Encode(policy Policy) (string, error) {
+	parts := make([]string, 0, len(c.pairs))
+	for _, p := range c.pairs {
+		key, value := p.Key, p.Value
+		switch policy {
+		case Escape:
+			key, value = escape(key), escape(value)
+		case Quote:
+			key = escape(key)
+			if HasMetaChars(value) {
+				value = quote(value)
+			}
+		case Reject:
+			if HasMetaChars(key) || HasMetaChars(value) {
+				return "", fmt.Errorf("%w: %q=%q", ErrMetaChars, key, value)
+			}
+		default:
+			return "", fmt.Errorf("cookie: unknown policy %d", policy)
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, "&"), nil
+}
+
+// String : escaped encoding, never fails
+func (c *Cookie) String() string {
+	s, _ := c.Encode(Escape)
+	return s
+}
+
+//
+// JSON
+//
+
+// MarshalJSON : JSON object with keys in cookie order
+func (c *Cookie) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, p := range c.pairs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(p.Key)
+		v, _ := json.Marshal(p.Value)
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// UnmarshalJSON : flat JSON object of strings, keeps key order
+func (c *Cookie) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return errors.New("cookie: JSON object expected")
+	}
+	nc := &Cookie{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := t.(string)
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("cookie: value of %q: %v", key, err)
+		}
+		if err := nc.add(key, value); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	*c = *nc
+	return nil
+}
@@ -0,0 +1,65 @@
+package cookie
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Profile : user profile of the ECB cut-and-paste challenge
+//
+//   profile_for("foo@bar.com") => email=foo@bar.com&uid=10&role=user
+type Profile struct {
+	Email string
+	UID   int
+	Role  string
+}
+
+// ProfileFor : profile of a new user
+func ProfileFor(email string) *Profile {
+	return &Profile{Email: email, UID: 10, Role: "user"}
+}
+
+// Cookie : profile as email, uid, role pairs
+func (p *Profile) Cookie() *Cookie {
+	return &Cookie{pairs: []Pair{
+		{"email", p.Email},
+		{"uid", strconv.Itoa(p.UID)},
+		{"role", p.Role},
+	}}
+}
+
+// Encode : k=v encoding of the profile under policy
+func (p *Profile) Encode(policy Policy) (string, error) {
+	return p.Cookie().Encode(policy)
+}
+
+// ProfileFromCookie : profile from parsed pairs
+// all of email, uid and role must be present, other keys are ignored
+func ProfileFromCookie(c *Cookie) (*Profile, error) {
+	p := &Profile{}
+	var ok bool
+	if p.Email, ok = c.Get("email"); !ok {
+		return nil, fmt.Errorf("cookie: profile has no email")
+	}
+	if p.Role, ok = c.Get("role"); !ok {
+		return nil, fmt.Errorf("cookie: profile has no role")
+	}
+	uid, ok := c.Get("uid")
+	if !ok {
+		return nil, fmt.Errorf("cookie: profile has no uid")
+	}
+	var err error
+	if p.UID, err = strconv.Atoi(uid); err != nil {
+		return nil, fmt.Errorf("cookie: profile uid: %v", err)
+	}
+	return p, nil
+}
+
+// ParseProfile : strict parse of an encoded profile
+func ParseProfile(s string) (*Profile, error) {
+	c, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return ProfileFromCookie(c)
+}
@@ -1,9 +1,12 @@
 package crytin
 
 import (
+	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
 )
@@ -148,7 +151,6 @@ func DecryptAesEcb(cb, key []byte) ([]byte, error) {
 	// key size 16, 24, or 32 bytes to select
 	//     AES-128, AES-192, or AES-256
 	PKCS7PadKey(&key, []uint{16, 24, 32})
-	ks := len(key)
 
 	c, err := aes.NewCipher(key)
 	if err != nil {
@@ -157,8 +159,10 @@ func DecryptAesEcb(cb, key []byte) ([]byte, error) {
 
 	pb := make([]byte, len(cb))
 	// decrypt block by block
-	for i := 0; i+ks <= len(cb); i += ks {
-		c.Decrypt(pb[i:i+ks], cb[i:i+ks])
+	// AES blocks are 16 bytes whatever the key size
+	bs := c.BlockSize()
+	for i := 0; i+bs <= len(cb); i += bs {
+		c.Decrypt(pb[i:i+bs], cb[i:i+bs])
 	}
 
 	RemovePadding(&pb)
@@ -175,18 +179,20 @@ func DecryptAesEcb(cb, key []byte) ([]byte, error) {
 // Attacks: replay
 func EncryptAesEcb(pb, key []byte) ([]byte, error) {
 	PKCS7PadKey(&key, []uint{16, 24, 32})
-	ks := len(key)
 	c, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
 
-	PKCS7Pad(&pb, uint(ks))
+	// pad to the AES block, not the key length
+	// strict, so block aligned input still gets a padding block
+	bs := c.BlockSize()
+	PKCS7PadStrict(&pb, uint(bs))
 	cb := make([]byte, len(pb))
 
 	// encrypt block by block
-	for i := 0; i+ks <= len(pb); i += ks {
-		c.Encrypt(cb[i:i+ks], pb[i:i+ks])
+	for i := 0; i+bs <= len(pb); i += bs {
+		c.Encrypt(cb[i:i+bs], pb[i:i+bs])
 	}
 	return cb, nil
 }
@@ -203,20 +209,19 @@ func EncryptAesEcb(pb, key []byte) ([]byte, error) {
 // Attacks: padding oracle attacks such as POODLE
 func EncryptAesCbc(pb, key, iv []byte) ([]byte, error) {
 	PKCS7PadKey(&key, []uint{16, 24, 32})
-	ks := len(key)
 	c, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
-	PKCS7Pad(&pb, uint(ks))
-	cb := make([]byte, len(pb))
-
-	// encrypt block by block
-	for i := 0; i+ks <= len(pb); i += ks {
-		c.Encrypt(cb[i:i+ks], XOR(pb[i:i+ks], iv))
-		iv = cb[i : i+ks] // iv for next block
+	if len(iv) < c.BlockSize() {
+		return nil, ErrBlockSize
 	}
-	return cb, nil
+	if err := CheckCBCIV(key, iv); err != nil {
+		return nil, err
+	}
+	// pad to the AES block, not the key length
+	PKCS7PadStrict(&pb, uint(c.BlockSize()))
+	return cbcEncrypt(c, pb, iv), nil
 }
 
 // DecryptAesCbc : AES-CBC mode symmetric cipher to decrypt
@@ -224,22 +229,103 @@ func EncryptAesCbc(pb, key, iv []byte) ([]byte, error) {
 // cb[i], key => xor(dec(), cb[i-1]) => pb[i]
 func DecryptAesCbc(cb, key, iv []byte) ([]byte, error) {
 	PKCS7PadKey(&key, []uint{16, 24, 32})
-	ks := len(key)
 	c, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
+	if len(iv) < c.BlockSize() {
+		return nil, ErrBlockSize
+	}
+	if err := CheckCBCIV(key, iv); err != nil {
+		return nil, err
+	}
+	pb := cbcDecrypt(c, cb, iv)
+	RemovePadding(&pb)
+	return pb, nil
+}
+
+// EncryptAesCbcRaw : AES-CBC encrypt without padding
+// pb must be a multiple of the block size
+func EncryptAesCbcRaw(pb, key, iv []byte) ([]byte, error) {
+	PKCS7PadKey(&key, []uint{16, 24, 32})
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(pb)%c.BlockSize() != 0 || len(iv) < c.BlockSize() {
+		return nil, ErrBlockSize
+	}
+	if err := CheckCBCIV(key, iv); err != nil {
+		return nil, err
+	}
+	return cbcEncrypt(c, pb, iv), nil
+}
+
+// DecryptAesCbcRaw : AES-CBC decrypt without removing padding
+// cb must be a multiple of the block size
+func DecryptAesCbcRaw(cb, key, iv []byte) ([]byte, error) {
+	PKCS7PadKey(&key, []uint{16, 24, 32})
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(cb) == 0 || len(cb)%c.BlockSize() != 0 || len(iv) < c.BlockSize() {
+		return nil, ErrBlockSize
+	}
+	if err := CheckCBCIV(key, iv); err != nil {
+		return nil, err
+	}
+	return cbcDecrypt(c, cb, iv), nil
+}
+
+// ErrIVIsKey : the CBC IV is (a prefix of) the key
+var ErrIVIsKey = errors.New("crytin: CBC IV must not be the key")
+
+// CheckCBCIV : refuses an IV equal to the key (or its first block)
+// IV = key lets anyone who sees one decryption recover the key, see AttackCBCKeyAsIV
+func CheckCBCIV(key, iv []byte) error {
+	n := aes.BlockSize
+	if len(key) < n || len(iv) < n {
+		return nil
+	}
+	if bytes.Equal(key[:n], iv[:n]) {
+		return ErrIVIsKey
+	}
+	return nil
+}
+
+// newAesCipher : AES block cipher, key restricted to 16, 24 or 32 bytes
+func newAesCipher(key []byte) (cipher.Block, error) {
+	PKCS7PadKey(&key, []uint{16, 24, 32})
+	return aes.NewCipher(key)
+}
+
+// cbcEncrypt : CBC chaining over the whole blocks of pb
+func cbcEncrypt(c cipher.Block, pb, iv []byte) []byte {
+	bs := c.BlockSize()
+	cb := make([]byte, len(pb))
+
+	// encrypt block by block
+	for i := 0; i+bs <= len(pb); i += bs {
+		c.Encrypt(cb[i:i+bs], XOR(pb[i:i+bs], iv[:bs]))
+		iv = cb[i : i+bs] // iv for next block
+	}
+	return cb
+}
 
+// cbcDecrypt : CBC chaining over the whole blocks of cb
+func cbcDecrypt(c cipher.Block, cb, iv []byte) []byte {
+	bs := c.BlockSize()
 	pb := make([]byte, len(cb))
-	dec := make([]byte, ks)
+	dec := make([]byte, bs)
+
 	// decrypt block by block
-	for i := 0; i+ks <= len(pb); i += ks {
-		c.Decrypt(dec, cb[i:i+ks])
-		copy(pb[i:i+ks], XOR(dec, iv))
-		iv = cb[i : i+ks] // iv for next block
+	for i := 0; i+bs <= len(pb); i += bs {
+		c.Decrypt(dec, cb[i:i+bs])
+		copy(pb[i:i+bs], XOR(dec, iv[:bs]))
+		iv = cb[i : i+bs] // iv for next block
 	}
-	RemovePadding(&pb)
-	return pb, nil
+	return pb
 }
 
 //bit padding: 100000
@@ -300,11 +386,46 @@ func PKCS7PadKey(pb *[]byte, sizes []uint) {
 	}
 }
 
+// PKCS7PadStrict : PKCS7 padding that always adds 1 to blockSize bytes
+// PKCS7Pad skips the padding when pb is already a multiple of blockSize,
+// strict unpadding (PKCS7Unpad) needs the full block of padding
+func PKCS7PadStrict(pb *[]byte, blockSize uint) {
+	pbLen := uint(len(*pb))
+	PKCS7Padding(pb, (pbLen/blockSize+1)*blockSize)
+}
+
+// ErrInvalidPadding : padding is not valid PKCS7
+var ErrInvalidPadding = errors.New("crytin: invalid PKCS7 padding")
+
+// ErrBlockSize : data is not a whole number of blocks
+var ErrBlockSize = errors.New("crytin: input not a multiple of the block size")
+
+// PKCS7Unpad : validates and removes PKCS7 padding
+//  n (1..blockSize) bytes of value n, anything else is ErrInvalidPadding
+func PKCS7Unpad(pb *[]byte, blockSize uint) error {
+	pbLen := len(*pb)
+	if pbLen == 0 || uint(pbLen)%blockSize != 0 {
+		return ErrBlockSize
+	}
+	n := int((*pb)[pbLen-1])
+	if n == 0 || uint(n) > blockSize {
+		return ErrInvalidPadding
+	}
+	for _, b := range (*pb)[pbLen-n:] {
+		if int(b) != n {
+			return ErrInvalidPadding
+		}
+	}
+	*pb = (*pb)[:pbLen-n]
+	return nil
+}
+
 // RemovePadding : Removes padding
 //  works for : ANSI X.923, ISO 10126, PKCS7
+//  up to a full AES block of padding (0x10), as PKCS7PadStrict adds
 func RemovePadding(pb *[]byte) {
 	b := (*pb)[len(*pb)-1]
-	if b > 0x0F {
+	if b > 0x10 || int(b) > len(*pb) {
 		return
 	} //check valid pad char
 	*pb = (*pb)[:len(*pb)-int(b)]
@@ -0,0 +1,110 @@
+package crytin
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+)
+
+// CTR mode
+//
+// key stream block i = enc(key, nonce || little-endian uint64 counter i)
+// cb = pb XOR key stream, so encrypt and decrypt are the same function
+//
+// No padding, any length works. Byte n of the stream only needs block n/16,
+// so the stream can be sought anywhere: random access like disk encryption.
+// Attacks: never reuse (key, nonce), the cipher text is XOR malleable
+
+// ErrNonceSize : CTR nonce must be half a block (8 bytes)
+var ErrNonceSize = errors.New("crytin: CTR nonce must be 8 bytes")
+
+// ErrOffset : stream offset out of range
+var ErrOffset = errors.New("crytin: offset out of range")
+
+// CtrStream : seekable AES-CTR key stream
+type CtrStream struct {
+	c     cipher.Block
+	nonce []byte
+}
+
+// NewAesCtr : AES-CTR key stream for key and an 8 byte nonce
+func NewAesCtr(key, nonce []byte) (*CtrStream, error) {
+	c, err := newAesCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != c.BlockSize()/2 {
+		return nil, ErrNonceSize
+	}
+	return &CtrStream{c: c, nonce: append([]byte{}, nonce...)}, nil
+}
+
+// keyBlock : key stream block for counter
+func (s *CtrStream) keyBlock(dst []byte, counter uint64) {
+	bs := s.c.BlockSize()
+	in := make([]byte, bs)
+	copy(in, s.nonce)
+	binary.LittleEndian.PutUint64(in[bs/2:], counter)
+	s.c.Encrypt(dst, in)
+}
+
+// XORKeyStreamAt : dst = src XOR key stream starting at stream offset
+// dst and src may overlap entirely, a negative offset is ErrOffset
+func (s *CtrStream) XORKeyStreamAt(dst, src []byte, offset int64) error {
+	if offset < 0 {
+		return ErrOffset
+	}
+	bs := int64(s.c.BlockSize())
+	ks := make([]byte, bs)
+	counter := uint64(offset / bs)
+	skip := int(offset % bs)
+
+	for i := 0; i < len(src); counter++ {
+		s.keyBlock(ks, counter)
+		for j := skip; j < int(bs) && i < len(src); j, i = j+1, i+1 {
+			dst[i] = src[i] ^ ks[j]
+		}
+		skip = 0
+	}
+	return nil
+}
+
+// EncryptAesCtr : AES-CTR mode, key size 16, 24 or 32 bytes, 8 byte nonce
+func EncryptAesCtr(pb, key, nonce []byte) ([]byte, error) {
+	s, err := NewAesCtr(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	cb := make([]byte, len(pb))
+	if err := s.XORKeyStreamAt(cb, pb, 0); err != nil {
+		return nil, err
+	}
+	return cb, nil
+}
+
+// DecryptAesCtr : same as EncryptAesCtr
+func DecryptAesCtr(cb, key, nonce []byte) ([]byte, error) {
+	return EncryptAesCtr(cb, key, nonce)
+}
+
+// EditAesCtr : re-encrypts newtext into a copy of cb at offset
+// only the key stream blocks under newtext are generated, cb may grow
+func EditAesCtr(cb, key, nonce []byte, offset int, newtext []byte) ([]byte, error) {
+	if offset < 0 || offset > len(cb) {
+		return nil, ErrOffset
+	}
+	s, err := NewAesCtr(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	n := len(cb)
+	if offset+len(newtext) > n {
+		n = offset + len(newtext)
+	}
+	out := make([]byte, n)
+	copy(out, cb)
+	if err := s.XORKeyStreamAt(out[offset:offset+len(newtext)], newtext, int64(offset)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
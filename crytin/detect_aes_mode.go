@@ -1,23 +1,96 @@
 package crytin
 
+import "sort"
+
+// ECBBlockSizes : candidate cipher block sizes
+// 8 bytes for DES/3DES/Blowfish, 16 bytes for AES (whatever the key size)
+var ECBBlockSizes = []int{8, 16}
+
+// ECBReport : how ECB-like the cipher text looks for one block size
+type ECBReport struct {
+	BlockSize  int
+	Blocks     int     // number of whole blocks
+	Repeats    int     // blocks equal to an earlier block
+	Duplicates [][]int // block indexes of each group of equal blocks
+	Score      float64 // Repeats / Blocks, 0 looks random, near 1 is a penguin
+}
+
+// Notes: a random cipher block repeating is a birthday event,
+// ~ n^2 / 2^(8*bs+1) for n blocks, so never for 16-byte blocks and practically
+// never for 8-byte blocks of a file. Any repeat is ECB (or a reused key stream).
+//
+// A 16-byte repeat is also two 8-byte repeats at the same offsets, so both
+// sizes score the same for AES-ECB; ties go to the larger block size.
+
+// AnalyzeECBBlockSize : ECB report of cb for one block size
+// trailing bytes that do not fill a block are ignored
+func AnalyzeECBBlockSize(cb []byte, bs int) ECBReport {
+	r := ECBReport{BlockSize: bs, Blocks: len(cb) / bs}
+
+	seen := map[string]int{} // block => index in r.Duplicates
+	first := map[string]int{}
+	for i := 0; i < r.Blocks; i++ {
+		block := string(cb[i*bs : (i+1)*bs])
+		j, ok := first[block]
+		if !ok {
+			first[block] = i
+			continue
+		}
+		r.Repeats++
+		if g, ok := seen[block]; ok {
+			r.Duplicates[g] = append(r.Duplicates[g], i)
+		} else {
+			seen[block] = len(r.Duplicates)
+			r.Duplicates = append(r.Duplicates, []int{j, i})
+		}
+	}
+	if r.Blocks > 0 {
+		r.Score = float64(r.Repeats) / float64(r.Blocks)
+	}
+	return r
+}
+
+// AnalyzeECB : ECB reports of cb for every candidate block size
+// the most likely block size comes first
+func AnalyzeECB(cb []byte) []ECBReport {
+	reports := make([]ECBReport, 0, len(ECBBlockSizes))
+	for _, bs := range ECBBlockSizes {
+		reports = append(reports, AnalyzeECBBlockSize(cb, bs))
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Score != reports[j].Score {
+			return reports[i].Score > reports[j].Score
+		}
+		return reports[i].BlockSize > reports[j].BlockSize
+	})
+	return reports
+}
+
 // DetectECB : detect ECB mode
-// returns the block size
+// returns the most likely block size
 func DetectECB(cb []byte) (bool, uint) {
-	//16, 24, or 32 key or block sizes
-
-	cbLen := len(cb)
-	for _, bs := range []int{16, 24, 32} {
-		if cbLen%bs == 0 {
-			blocks := map[string]bool{}
-			for i := 0; i < cbLen; i += bs {
-				block := string(cb[i : i+bs])
-				_, exists := blocks[block]
-				if exists {
-					return true, uint(bs)
-				}
-				blocks[block] = true
-			}
-		}
+	best := AnalyzeECB(cb)[0]
+	if best.Repeats == 0 {
+		return false, uint(0)
+	}
+	return true, uint(best.BlockSize)
+}
+
+// ECBRank : position of one cipher text in a ranking
+type ECBRank struct {
+	Index  int       // index in the ranked dataset
+	Report ECBReport // most likely block size report
+}
+
+// RankECB : sorts a dataset by how ECB-like each cipher text is
+// most ECB-like first, equal scores keep dataset order
+func RankECB(cbs [][]byte) []ECBRank {
+	ranks := make([]ECBRank, len(cbs))
+	for i, cb := range cbs {
+		ranks[i] = ECBRank{Index: i, Report: AnalyzeECB(cb)[0]}
 	}
-	return false, uint(0)
+	sort.SliceStable(ranks, func(i, j int) bool {
+		return ranks[i].Report.Score > ranks[j].Report.Score
+	})
+	return ranks
 }
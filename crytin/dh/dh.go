@@ -0,0 +1,103 @@
+// Package dh : Diffie-Hellman over math/big
+//
+//   A = g^a mod p, B = g^b mod p
+//   s = B^a mod p = A^b mod p = g^ab mod p
+//
+// Groups: the RFC 3526 MODP groups (MODP) and a toy group with a tiny p
+// for fast tests. Private keys are read from an io.Reader so exchanges can
+// be replayed with crytin.NewSeededReader. The shared secret becomes an
+// AES key through a KDF (SHA-1 or SHA-256).
+//
+// Shared does not validate the peer's public key on purpose: the MITM
+// attacks of set 5 feed it p, 1 or p-1. CheckPublic is the fix.
+package dh
+
+import (
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+// Errors returned by CheckPublic and MODP
+var (
+	ErrPublicKey = errors.New("dh: public key out of range")
+	ErrGroup     = errors.New("dh: no such MODP group")
+)
+
+var one = big.NewInt(1)
+
+// Group : prime modulus P and generator G
+type Group struct {
+	Name string
+	P    *big.Int
+	G    *big.Int
+}
+
+// Toy : p = 37, g = 5, every exponent fits in a byte
+var Toy = &Group{Name: "toy-37", P: big.NewInt(37), G: big.NewInt(5)}
+
+// KeyPair : private exponent and public g^private mod p
+type KeyPair struct {
+	Group   *Group
+	Private *big.Int
+	Public  *big.Int
+}
+
+// GenerateKey : private key in [1, p-2] read from rnd (nil for crypto/rand)
+func GenerateKey(g *Group, rnd io.Reader) (*KeyPair, error) {
+	limit := new(big.Int).Sub(g.P, big.NewInt(2))
+	x, err := randInt(rnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	x.Add(x, one)
+	return NewKeyPair(g, x), nil
+}
+
+// NewKeyPair : key pair for a given private exponent
+func NewKeyPair(g *Group, private *big.Int) *KeyPair {
+	return &KeyPair{
+		Group:   g,
+		Private: new(big.Int).Set(private),
+		Public:  new(big.Int).Exp(g.G, private, g.P),
+	}
+}
+
+// Shared : peer^private mod p, peer is not checked (see CheckPublic)
+func (k *KeyPair) Shared(peer *big.Int) *big.Int {
+	return new(big.Int).Exp(peer, k.Private, k.Group.P)
+}
+
+// CheckPublic : 1 < y < p-1, rules out the degenerate keys 0, 1, p-1 and
+// anything not reduced mod p
+func CheckPublic(g *Group, y *big.Int) error {
+	pm1 := new(big.Int).Sub(g.P, one)
+	if y.Cmp(one) <= 0 || y.Cmp(pm1) >= 0 {
+		return ErrPublicKey
+	}
+	return nil
+}
+
+// randInt : uniform in [0, limit) by rejection sampling, limit > 0
+// crypto/rand.Int is not used, it may ignore a custom reader
+func randInt(rnd io.Reader, limit *big.Int) (*big.Int, error) {
+	if limit.Sign() <= 0 {
+		return nil, errors.New("dh: group too small")
+	}
+	bits := limit.BitLen()
+	n := (bits + 7) / 8
+	for {
+		b, err := crytin.RandomBytes(rnd, n)
+		if err != nil {
+			return nil, err
+		}
+		// drop the bits above limit's top bit
+		b[0] &= byte(0xff >> (8*n - bits))
+		x := new(big.Int).SetBytes(b)
+		if x.Cmp(limit) < 0 {
+			return x, nil
+		}
+	}
+}
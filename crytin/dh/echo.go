@@ -0,0 +1,239 @@
+package dh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/srinivengala/cryptopals/crytin"
+	"github.com/srinivengala/cryptopals/crytin/netsim"
+)
+
+// Echo protocol (challenges 34, 35)
+//
+// key exchange, challenge 34        negotiated, challenge 35
+//   A->B "pga" p, g, A                A->B "pg"  p, g
+//   B->A "B"   B                      B->A "ack" p, g
+//                                     A->B "A"   A
+//                                     B->A "B"   B
+// then for every message
+//   A->B "msg"  AES-CBC(KDF(s), iv, msg) || iv
+//   B->A "echo" AES-CBC(KDF(s), iv', msg) || iv'
+// and A checks the echo. Neither side checks the other's public key.
+
+// Message types of the echo protocol
+const (
+	MsgPGA  = "pga"
+	MsgPG   = "pg"
+	MsgAck  = "ack"
+	MsgA    = "A"
+	MsgB    = "B"
+	MsgText = "msg"
+	MsgEcho = "echo"
+)
+
+// Errors of the echo protocol
+var (
+	ErrEcho        = errors.New("dh: echo does not match the message")
+	ErrNegotiation = errors.New("dh: peer acknowledged other parameters")
+	ErrPayload     = errors.New("dh: unexpected message payload")
+)
+
+// Params : p and g, payload of "pg" and "ack"
+type Params struct {
+	P, G *big.Int
+}
+
+// KeyExchange : p, g and A, payload of "pga"
+type KeyExchange struct {
+	P, G, A *big.Int
+}
+
+// Echo : one side of the echo protocol
+type Echo struct {
+	Group     *Group    // client only, the server takes p and g from the wire
+	Negotiate bool      // challenge 35 flavour
+	KDF       KDF       // nil for SHA1KDF
+	Rand      io.Reader // keys and IVs, nil for crypto/rand
+}
+
+func (e *Echo) kdf() KDF {
+	if e.KDF == nil {
+		return SHA1KDF
+	}
+	return e.KDF
+}
+
+// Client : key exchange with server, then sends msgs one by one and checks the echoes
+func (e *Echo) Client(ep *netsim.Endpoint, server string, msgs [][]byte) error {
+	key, err := GenerateKey(e.Group, e.Rand)
+	if err != nil {
+		return err
+	}
+
+	if e.Negotiate {
+		if err := ep.Send(server, MsgPG, Params{P: e.Group.P, G: e.Group.G}); err != nil {
+			return err
+		}
+		m, err := ep.Expect(MsgAck)
+		if err != nil {
+			return err
+		}
+		ack, ok := m.Payload.(Params)
+		if !ok {
+			return ErrPayload
+		}
+		if ack.P.Cmp(e.Group.P) != 0 || ack.G.Cmp(e.Group.G) != 0 {
+			return ErrNegotiation
+		}
+		if err := ep.Send(server, MsgA, key.Public); err != nil {
+			return err
+		}
+	} else {
+		if err := ep.Send(server, MsgPGA, KeyExchange{P: e.Group.P, G: e.Group.G, A: key.Public}); err != nil {
+			return err
+		}
+	}
+	m, err := ep.Expect(MsgB)
+	if err != nil {
+		return err
+	}
+	peer, ok := m.Payload.(*big.Int)
+	if !ok {
+		return ErrPayload
+	}
+	aesKey := e.kdf()(key.Shared(peer))
+
+	for _, msg := range msgs {
+		sealed, err := Seal(aesKey, msg, e.Rand)
+		if err != nil {
+			return err
+		}
+		if err := ep.Send(server, MsgText, sealed); err != nil {
+			return err
+		}
+		m, err := ep.Expect(MsgEcho)
+		if err != nil {
+			return err
+		}
+		sealed, ok := m.Payload.([]byte)
+		if !ok {
+			return ErrPayload
+		}
+		echo, err := Open(aesKey, sealed)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(echo, msg) {
+			return fmt.Errorf("%w: %q", ErrEcho, echo)
+		}
+	}
+	return nil
+}
+
+// Server : key exchange with client, then echoes n messages
+func (e *Echo) Server(ep *netsim.Endpoint, client string, n int) error {
+	var group *Group
+	var peer *big.Int
+	if e.Negotiate {
+		m, err := ep.Expect(MsgPG)
+		if err != nil {
+			return err
+		}
+		params, ok := m.Payload.(Params)
+		if !ok {
+			return ErrPayload
+		}
+		group = &Group{Name: "negotiated", P: params.P, G: params.G}
+		if err := ep.Send(client, MsgAck, params); err != nil {
+			return err
+		}
+		if m, err = ep.Expect(MsgA); err != nil {
+			return err
+		}
+		if peer, ok = m.Payload.(*big.Int); !ok {
+			return ErrPayload
+		}
+	} else {
+		m, err := ep.Expect(MsgPGA)
+		if err != nil {
+			return err
+		}
+		kx, ok := m.Payload.(KeyExchange)
+		if !ok {
+			return ErrPayload
+		}
+		group = &Group{Name: "received", P: kx.P, G: kx.G}
+		peer = kx.A
+	}
+
+	key, err := GenerateKey(group, e.Rand)
+	if err != nil {
+		return err
+	}
+	if err := ep.Send(client, MsgB, key.Public); err != nil {
+		return err
+	}
+	aesKey := e.kdf()(key.Shared(peer))
+
+	for i := 0; i < n; i++ {
+		m, err := ep.Expect(MsgText)
+		if err != nil {
+			return err
+		}
+		sealed, ok := m.Payload.([]byte)
+		if !ok {
+			return ErrPayload
+		}
+		msg, err := Open(aesKey, sealed)
+		if err != nil {
+			return err
+		}
+		echo, err := Seal(aesKey, msg, e.Rand)
+		if err != nil {
+			return err
+		}
+		if err := ep.Send(client, MsgEcho, echo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Seal : AES-CBC(key, iv, PKCS7(msg)) || iv with a random iv
+func Seal(key, msg []byte, rnd io.Reader) ([]byte, error) {
+	iv, err := crytin.RandomIV(rnd)
+	if err != nil {
+		return nil, err
+	}
+	return sealIV(key, iv, msg)
+}
+
+// sealIV : AES-CBC(key, iv, PKCS7(msg)) || iv
+func sealIV(key, iv, msg []byte) ([]byte, error) {
+	pb := append([]byte{}, msg...)
+	crytin.PKCS7PadStrict(&pb, crytin.IVSize)
+	cb, err := crytin.EncryptAesCbcRaw(pb, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return append(cb, iv...), nil
+}
+
+// Open : decrypts cipher text || iv, padding strictly checked
+func Open(key, sealed []byte) ([]byte, error) {
+	if len(sealed) < 2*crytin.IVSize {
+		return nil, crytin.ErrBlockSize
+	}
+	n := len(sealed) - crytin.IVSize
+	pb, err := crytin.DecryptAesCbcRaw(sealed[:n], key, sealed[n:])
+	if err != nil {
+		return nil, err
+	}
+	if err := crytin.PKCS7Unpad(&pb, crytin.IVSize); err != nil {
+		return nil, err
+	}
+	return pb, nil
+}
@@ -0,0 +1,20 @@
+package dh
+
+import (
+	"math/big"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+// KDF : shared secret to a 16 byte AES-128 key
+type KDF func(s *big.Int) []byte
+
+// SHA1KDF : SHA1(s as big endian bytes)[0:16], as in challenge 34
+func SHA1KDF(s *big.Int) []byte {
+	return crytin.SHA1Sum(s.Bytes())[:crytin.KeySize]
+}
+
+// SHA256KDF : SHA256(s as big endian bytes)[0:16]
+func SHA256KDF(s *big.Int) []byte {
+	return crytin.SHA256Func.Sum(s.Bytes())[:crytin.KeySize]
+}
@@ -0,0 +1,221 @@
+package dh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/srinivengala/cryptopals/crytin"
+	"github.com/srinivengala/cryptopals/crytin/netsim"
+)
+
+// MITM attacks on the echo protocol
+//
+// Parameter injection (challenge 34), key exchange flavour:
+//   M swaps A and B for p, both sides compute s = p^x mod p = 0
+//
+// Malicious g (challenge 35), negotiated flavour: B gets g', A gets an ACK
+// with the real g back, B gets A' = g' mod p instead of A
+//   g' = 1   : B = 1,                    s(A) = 1^a = 1,      s(B) = 1^b = 1
+//   g' = p   : B = 0,                    s(A) = 0^a = 0,      s(B) = 0^b = 0
+//   g' = p-1 : B = (p-1)^b in {1, p-1},  s(A) = B^a in {1, p-1} (parity of a),
+//                                        s(B) = (p-1)^b = B
+// With g' = p-1 the two sides may disagree and M can not tell s(A) from
+// the padding (a wrong key gives valid padding 1 time in 256). M sends 1
+// to A instead of B, so s(A) = 1 for sure, and re-encrypts everything it
+// relays for the other side.
+//
+// Fix: CheckPublic on every received key, and never let the peer pick g.
+
+// Attack : what the MITM does to the key exchange
+type Attack int
+
+// Attacks of challenges 34 and 35
+const (
+	ParameterInjection Attack = iota // A and B swapped for p
+	MaliciousG1                      // g = 1
+	MaliciousGP                      // g = p
+	MaliciousGPMinus1                // g = p - 1
+)
+
+func (a Attack) String() string {
+	switch a {
+	case ParameterInjection:
+		return "parameter injection"
+	case MaliciousG1:
+		return "g = 1"
+	case MaliciousGP:
+		return "g = p"
+	case MaliciousGPMinus1:
+		return "g = p-1"
+	}
+	return fmt.Sprintf("Attack(%d)", int(a))
+}
+
+// Negotiated : does the attack need the challenge 35 flavour of the protocol
+func (a Attack) Negotiated() bool {
+	return a != ParameterInjection
+}
+
+// Plaintext : a message M decrypted on the wire
+type Plaintext struct {
+	From, To string
+	Text     []byte
+}
+
+// MITM : netsim.Adversary running one attack on the echo protocol
+type MITM struct {
+	attack Attack
+	kdf    KDF
+
+	p, g   *big.Int // real parameters
+	evilG  *big.Int // g' sent to B
+	keyA   []byte   // A's AES key
+	keyB   []byte   // B's AES key
+	plains []Plaintext
+	err    error
+}
+
+// NewMITM : adversary running attack, kdf as used by the parties (nil for SHA1KDF)
+func NewMITM(attack Attack, kdf KDF) *MITM {
+	if kdf == nil {
+		kdf = SHA1KDF
+	}
+	return &MITM{attack: attack, kdf: kdf}
+}
+
+// Transcript : every message decrypted so far, in order
+func (m *MITM) Transcript() ([]Plaintext, error) {
+	return append([]Plaintext{}, m.plains...), m.err
+}
+
+// Intercept : implements netsim.Adversary
+func (m *MITM) Intercept(msg netsim.Message) []netsim.Message {
+	if m.err != nil {
+		return []netsim.Message{msg}
+	}
+	out, err := m.intercept(msg)
+	if err != nil {
+		m.err = fmt.Errorf("dh: %v on %s: %w", m.attack, msg.Type, err)
+		return []netsim.Message{msg}
+	}
+	return []netsim.Message{out}
+}
+
+func (m *MITM) intercept(msg netsim.Message) (netsim.Message, error) {
+	switch msg.Type {
+	case MsgPGA:
+		kx, ok := msg.Payload.(KeyExchange)
+		if !ok || m.attack != ParameterInjection {
+			return msg, ErrPayload
+		}
+		m.p, m.g = kx.P, kx.G
+		msg.Payload = KeyExchange{P: kx.P, G: kx.G, A: kx.P}
+
+	case MsgPG:
+		params, ok := msg.Payload.(Params)
+		if !ok || !m.attack.Negotiated() {
+			return msg, ErrPayload
+		}
+		m.p, m.g = params.P, params.G
+		m.evilG = m.maliciousG()
+		msg.Payload = Params{P: params.P, G: m.evilG}
+
+	case MsgAck:
+		// A must see the g it asked for
+		msg.Payload = Params{P: m.p, G: m.g}
+
+	case MsgA:
+		if m.evilG == nil {
+			return msg, ErrPayload
+		}
+		msg.Payload = new(big.Int).Mod(m.evilG, m.p)
+
+	case MsgB:
+		b, ok := msg.Payload.(*big.Int)
+		if !ok || m.p == nil {
+			return msg, ErrPayload
+		}
+		switch m.attack {
+		case ParameterInjection:
+			msg.Payload = new(big.Int).Set(m.p)
+		case MaliciousGPMinus1:
+			msg.Payload = big.NewInt(1)
+		}
+		m.predict(b)
+
+	case MsgText, MsgEcho:
+		sealed, ok := msg.Payload.([]byte)
+		if !ok || m.keyB == nil {
+			return msg, ErrPayload
+		}
+		from, to := m.keyA, m.keyB
+		if msg.Type == MsgEcho {
+			from, to = to, from
+		}
+		text, err := Open(from, sealed)
+		if err != nil {
+			return msg, err
+		}
+		m.plains = append(m.plains, Plaintext{From: msg.From, To: msg.To, Text: text})
+
+		// re-encrypt for the other side, same iv
+		if !bytes.Equal(from, to) {
+			iv := sealed[len(sealed)-crytin.IVSize:]
+			if msg.Payload, err = sealIV(to, iv, text); err != nil {
+				return msg, err
+			}
+		}
+	}
+	return msg, nil
+}
+
+// maliciousG : g' for B
+func (m *MITM) maliciousG() *big.Int {
+	switch m.attack {
+	case MaliciousG1:
+		return big.NewInt(1)
+	case MaliciousGP:
+		return new(big.Int).Set(m.p)
+	default:
+		return new(big.Int).Sub(m.p, one)
+	}
+}
+
+// predict : the shared secrets both sides end up with, b is B's public key
+// as B sent it
+func (m *MITM) predict(b *big.Int) {
+	switch m.attack {
+	case ParameterInjection, MaliciousGP:
+		m.keyB = m.kdf(new(big.Int))
+		m.keyA = m.keyB
+	case MaliciousG1:
+		m.keyB = m.kdf(big.NewInt(1))
+		m.keyA = m.keyB
+	case MaliciousGPMinus1:
+		// s(B) = (p-1)^b = B, A got 1: s(A) = 1^a = 1
+		m.keyB = m.kdf(b)
+		m.keyA = m.kdf(big.NewInt(1))
+	}
+}
+
+// RunMITM : runs the echo protocol over group with msgs between A and B,
+// attack on the wire, and returns what M decrypted
+// rnd gives both parties' keys and IVs (nil for crypto/rand)
+func RunMITM(attack Attack, group *Group, msgs [][]byte, rnd io.Reader) ([]Plaintext, error) {
+	mitm := NewMITM(attack, nil)
+	net := netsim.New(mitm)
+	a, b := net.Join("A"), net.Join("B")
+	client := &Echo{Group: group, Negotiate: attack.Negotiated(), Rand: rnd}
+	server := &Echo{Negotiate: attack.Negotiated(), Rand: rnd}
+
+	err := netsim.Run(
+		func() error { return client.Client(a, "B", msgs) },
+		func() error { return server.Server(b, "A", len(msgs)) },
+	)
+	if err != nil {
+		return nil, err
+	}
+	return mitm.Transcript()
+}
@@ -0,0 +1,93 @@
+package dh
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// RFC 3526 MODP groups
+//
+//   p = 2^n - 2^(n-64) - 1 + 2^64 * ( floor(2^(n-130) pi) + k ),  g = 2
+//
+// The top and bottom 64 bits are all ones, the middle comes from pi so
+// nobody could have picked a weak prime. k is the smallest offset making
+// p a safe prime ((p-1)/2 prime too). Computing p from pi avoids pasting
+// kilobytes of hex. The 1536 bit group is the "NIST" prime of challenge 33.
+
+// modpK : k of every group, by bit size
+var modpK = map[int]int64{
+	1536: 741804,
+	2048: 124476,
+	3072: 1690314,
+	4096: 240904,
+	6144: 929484,
+	8192: 4743158,
+}
+
+// MODPSizes : bit sizes of the RFC 3526 groups
+var MODPSizes = []int{1536, 2048, 3072, 4096, 6144, 8192}
+
+var (
+	modpMu     sync.Mutex
+	modpGroups = map[int]*Group{}
+	piOnce     sync.Once
+	piFixed    *big.Int // floor(pi * 2^piBits)
+)
+
+const piBits = 8192 - 130
+
+// MODP : the RFC 3526 group of bits bits
+func MODP(bits int) (*Group, error) {
+	k, ok := modpK[bits]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d bits", ErrGroup, bits)
+	}
+
+	modpMu.Lock()
+	defer modpMu.Unlock()
+	if g, ok := modpGroups[bits]; ok {
+		return g, nil
+	}
+
+	piOnce.Do(func() { piFixed = pi(piBits) })
+	p := new(big.Int).Rsh(piFixed, uint(piBits-(bits-130)))
+	p.Add(p, big.NewInt(k))
+	p.Lsh(p, 64)
+	p.Add(p, new(big.Int).Lsh(one, uint(bits)))
+	p.Sub(p, new(big.Int).Lsh(one, uint(bits-64)))
+	p.Sub(p, one)
+
+	g := &Group{Name: fmt.Sprintf("modp-%d", bits), P: p, G: big.NewInt(2)}
+	modpGroups[bits] = g
+	return g, nil
+}
+
+// pi : floor(pi * 2^bits), Machin: pi = 16 atan(1/5) - 4 atan(1/239)
+func pi(bits uint) *big.Int {
+	const guard = 64
+	prec := bits + guard
+	p := new(big.Int).Mul(atanInv(5, prec), big.NewInt(16))
+	p.Sub(p, new(big.Int).Mul(atanInv(239, prec), big.NewInt(4)))
+	return p.Rsh(p, guard)
+}
+
+// atanInv : atan(1/x) * 2^prec, x^-1 - x^-3/3 + x^-5/5 - ...
+func atanInv(x int64, prec uint) *big.Int {
+	sum := new(big.Int)
+	power := new(big.Int).Lsh(one, prec) // 2^prec / x^(2i+1)
+	bx := big.NewInt(x)
+	x2 := big.NewInt(x * x)
+	power.Quo(power, bx)
+	term := new(big.Int)
+	for i := int64(0); power.Sign() != 0; i++ {
+		term.Quo(power, big.NewInt(2*i+1))
+		if i%2 == 0 {
+			sum.Add(sum, term)
+		} else {
+			sum.Sub(sum, term)
+		}
+		power.Quo(power, x2)
+	}
+	return sum
+}
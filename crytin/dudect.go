@@ -0,0 +1,131 @@
+package crytin
+
+import (
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Timing leak detection (dudect)
+//
+// "Dude, is my code constant time?" Reparaz, Balasch, Verbauwhede 2017
+//
+// Time a function on two classes of input, typically a fixed input and
+// random ones, in random order. Constant time code gives the same timing
+// distribution for both. Welch's t-test on the two samples:
+//   t = (mean A - mean B) / sqrt(var A / n A + var B / n B)
+// |t| above 4.5 : the distributions differ, the function likely leaks.
+// The slowest measurements (interrupts, GC) are cropped before the test.
+
+// LeakThreshold : |t| above which a function is reported as leaky
+const LeakThreshold = 4.5
+
+// LeakTest : a function and its two input classes
+type LeakTest struct {
+	Run  func(in []byte)
+	A, B func() []byte // input generators, called before any timing
+	N    int           // measurements per class, 0 for 10000
+	// Repeat : calls of Run per measurement, 0 for 16
+	// makes fast functions measurable above the timer resolution
+	Repeat int
+	// Crop : fraction of the slowest measurements dropped, 0 for 0.1
+	Crop float64
+	Rand io.Reader // class order, nil for crypto/rand
+}
+
+// LeakReport : outcome of DetectTimingLeak
+type LeakReport struct {
+	N            int     // measurements per class kept
+	MeanA, MeanB float64 // ns per measurement
+	T            float64 // Welch's t
+	Leaky        bool    // |T| > LeakThreshold
+}
+
+// DetectTimingLeak : dudect style test of test.Run on the classes A and B
+func DetectTimingLeak(test LeakTest) (LeakReport, error) {
+	if test.N <= 0 {
+		test.N = 10000
+	}
+	if test.Repeat <= 0 {
+		test.Repeat = 16
+	}
+	if test.Crop <= 0 {
+		test.Crop = 0.1
+	}
+
+	// inputs and class order first, only Run is timed
+	order, err := RandomBytes(test.Rand, 2*test.N)
+	if err != nil {
+		return LeakReport{}, err
+	}
+	inputs := make([][]byte, 2*test.N)
+	for i := range inputs {
+		if order[i]&1 == 0 {
+			inputs[i] = test.A()
+		} else {
+			inputs[i] = test.B()
+		}
+	}
+
+	times := make([]float64, len(inputs))
+	for i, in := range inputs {
+		start := time.Now()
+		for r := 0; r < test.Repeat; r++ {
+			test.Run(in)
+		}
+		times[i] = float64(time.Since(start).Nanoseconds())
+	}
+
+	cut := percentile(times, 1-test.Crop)
+	var a, b welford
+	for i, t := range times {
+		if t > cut {
+			continue
+		}
+		if order[i]&1 == 0 {
+			a.add(t)
+		} else {
+			b.add(t)
+		}
+	}
+
+	r := LeakReport{N: a.n, MeanA: a.mean, MeanB: b.mean}
+	if b.n < r.N {
+		r.N = b.n
+	}
+	if se := math.Sqrt(a.variance()/float64(a.n) + b.variance()/float64(b.n)); se > 0 {
+		r.T = (a.mean - b.mean) / se
+	}
+	r.Leaky = math.Abs(r.T) > LeakThreshold
+	return r, nil
+}
+
+// welford : running mean and variance
+type welford struct {
+	n    int
+	mean float64
+	m2   float64
+}
+
+func (w *welford) add(x float64) {
+	w.n++
+	d := x - w.mean
+	w.mean += d / float64(w.n)
+	w.m2 += d * (x - w.mean)
+}
+
+func (w *welford) variance() float64 {
+	if w.n < 2 {
+		return 0
+	}
+	return w.m2 / float64(w.n-1)
+}
+
+// percentile : value below which fraction p of xs lies
+func percentile(xs []float64, p float64) float64 {
+	s := append([]float64{}, xs...)
+	sort.Float64s(s)
+	i := int(p * float64(len(s)-1))
+	return s[i]
+}
@@ -0,0 +1,35 @@
+package crytin
+
+// HMAC (RFC 2104)
+//
+//   H((key XOR opad) || H((key XOR ipad) || message))
+// key longer than a block is hashed first, shorter is zero padded.
+// The outer hash starts from a secret state, so no length extension.
+
+// HMAC : HMAC of message under key with any MDFunc
+func HMAC(f *MDFunc, key, message []byte) []byte {
+	if len(key) > f.BlockSize {
+		key = f.Sum(key)
+	}
+	ipad := make([]byte, f.BlockSize)
+	opad := make([]byte, f.BlockSize)
+	copy(ipad, key)
+	copy(opad, key)
+	for i := range ipad {
+		ipad[i] ^= 0x36
+		opad[i] ^= 0x5c
+	}
+
+	inner := f.New()
+	inner.Write(ipad)
+	inner.Write(message)
+	outer := f.New()
+	outer.Write(opad)
+	outer.Write(inner.Sum(nil))
+	return outer.Sum(nil)
+}
+
+// HMACSHA1 : HMAC-SHA1
+func HMACSHA1(key, message []byte) []byte {
+	return HMAC(SHA1Func, key, message)
+}
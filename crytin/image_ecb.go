@@ -0,0 +1,126 @@
+package crytin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// ECB penguin
+//
+// Encrypt only the pixels of an uncompressed image and keep the header,
+// the result is still a viewable image. Under ECB equal pixel runs give
+// equal cipher blocks, so the outline of the picture survives encryption.
+// Under CBC or CTR the pixels look like noise.
+//
+// Supported formats, no pixel decoding needed:
+//   PPM : binary P6 (and P5 grey), "P6 <width> <height> <maxval>" + raw pixels
+//   BMP : BITMAPINFOHEADER style with BI_RGB or BI_BITFIELDS (no RLE),
+//         pixel data starts at the offset stored in the file header
+
+// ImageEncrypter : encrypts pixel bytes, wraps any crytin mode
+// for example func(pb []byte) ([]byte, error) { return EncryptAesEcb(pb, key) }
+type ImageEncrypter func(pb []byte) ([]byte, error)
+
+// ErrImageFormat : not an uncompressed PPM or BMP image
+var ErrImageFormat = errors.New("crytin: not an uncompressed PPM or BMP image")
+
+// SplitImage : splits an image into header and pixel data
+func SplitImage(img []byte) (header, pixels []byte, err error) {
+	switch {
+	case bytes.HasPrefix(img, []byte("P6")), bytes.HasPrefix(img, []byte("P5")):
+		return splitPPM(img)
+	case bytes.HasPrefix(img, []byte("BM")):
+		return splitBMP(img)
+	}
+	return nil, nil, ErrImageFormat
+}
+
+// splitPPM : magic, width, height, maxval separated by whitespace
+// and comments, then a single whitespace byte before the pixels
+func splitPPM(img []byte) (header, pixels []byte, err error) {
+	i := 2
+	for fields := 0; fields < 3; fields++ {
+		// skip whitespace and # comments
+		for i < len(img) && (isSpace(img[i]) || img[i] == '#') {
+			if img[i] == '#' {
+				for i < len(img) && img[i] != '\n' {
+					i++
+				}
+				continue
+			}
+			i++
+		}
+		start := i
+		for i < len(img) && img[i] >= '0' && img[i] <= '9' {
+			i++
+		}
+		if start == i {
+			return nil, nil, fmt.Errorf("%w: bad PPM header", ErrImageFormat)
+		}
+	}
+	if i >= len(img) || !isSpace(img[i]) {
+		return nil, nil, fmt.Errorf("%w: bad PPM header", ErrImageFormat)
+	}
+	i++
+	return img[:i], img[i:], nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// splitBMP : pixel offset at 10, compression at 30 (little endian)
+func splitBMP(img []byte) (header, pixels []byte, err error) {
+	if len(img) < 34 {
+		return nil, nil, fmt.Errorf("%w: short BMP header", ErrImageFormat)
+	}
+	offset := binary.LittleEndian.Uint32(img[10:14])
+	compression := binary.LittleEndian.Uint32(img[30:34])
+	// 0 = BI_RGB, 3 = BI_BITFIELDS
+	if compression != 0 && compression != 3 {
+		return nil, nil, fmt.Errorf("%w: compressed BMP (%d)", ErrImageFormat, compression)
+	}
+	if offset < 34 || int(offset) > len(img) {
+		return nil, nil, fmt.Errorf("%w: bad BMP pixel offset %d", ErrImageFormat, offset)
+	}
+	return img[:offset], img[offset:], nil
+}
+
+// EncryptImage : encrypts the pixel data of a PPM or BMP image
+// the header is kept and the cipher text is cut to the pixel data length
+// (padding would not fit in the image) so the result stays viewable
+func EncryptImage(img []byte, enc ImageEncrypter) ([]byte, error) {
+	header, pixels, err := SplitImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	cb, err := enc(append([]byte{}, pixels...))
+	if err != nil {
+		return nil, err
+	}
+	if len(cb) < len(pixels) {
+		return nil, fmt.Errorf("crytin: encrypter returned %d bytes for %d pixel bytes", len(cb), len(pixels))
+	}
+
+	out := make([]byte, 0, len(header)+len(pixels))
+	out = append(out, header...)
+	out = append(out, cb[:len(pixels)]...)
+	return out, nil
+}
+
+// EncryptImageFile : reads image from in, writes the encrypted image to out
+func EncryptImageFile(in, out string, enc ImageEncrypter) error {
+	img, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	cimg, err := EncryptImage(img, enc)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, cimg, 0644)
+}
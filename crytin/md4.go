@@ -0,0 +1,117 @@
+package crytin
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// MD4 (RFC 1320)
+//
+// state a,b,c,d, 64 byte blocks, 3 rounds of 16 steps, little endian
+// Same Merkle-Damgard shape as SHA-1, so the same length extension works.
+
+const (
+	// MD4Size : digest size
+	MD4Size = 16
+	// MD4BlockSize : block size
+	MD4BlockSize = 64
+)
+
+// md4IV : a,b,c,d little endian, MD5 starts from the same state
+var md4IV = []byte{
+	0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef,
+	0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10,
+}
+
+// MD4Func : MD4 as an MDFunc
+var MD4Func = &MDFunc{
+	Name:      "MD4",
+	BlockSize: MD4BlockSize,
+	IV:        md4IV,
+	Compress:  md4Compress,
+	Pad:       mdPad(MD4BlockSize, binary.LittleEndian),
+}
+
+// MD4 : MD4 with injectable state, implements hash.Hash
+// a thin wrapper over MDHash, the state as a,b,c,d
+type MD4 struct {
+	*MDHash
+}
+
+// NewMD4 : MD4 from the standard initial state
+func NewMD4() *MD4 {
+	return &MD4{MD4Func.New()}
+}
+
+// SetState : continue from h as if length bytes were hashed already
+// length must be a multiple of the block size (padding included)
+func (d *MD4) SetState(h [4]uint32, length uint64) error {
+	return d.MDHash.SetState(md4StateBytes(h), length)
+}
+
+// MD4State : a,b,c,d held in a digest
+func MD4State(digest []byte) ([4]uint32, error) {
+	var h [4]uint32
+	if len(digest) != MD4Size {
+		return h, ErrDigestSize
+	}
+	for i := range h {
+		h[i] = binary.LittleEndian.Uint32(digest[4*i:])
+	}
+	return h, nil
+}
+
+// md4StateBytes : digest of state words
+func md4StateBytes(h [4]uint32) []byte {
+	b := make([]byte, 4*len(h))
+	for i, v := range h {
+		binary.LittleEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// MD4Sum : MD4 digest of b
+func MD4Sum(b []byte) []byte {
+	return MD4Func.Sum(b)
+}
+
+var (
+	md4Shift = [3][4]int{{3, 7, 11, 19}, {3, 5, 9, 13}, {3, 9, 11, 15}}
+	md4Index = [3][16]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		{0, 4, 8, 12, 1, 5, 9, 13, 2, 6, 10, 14, 3, 7, 11, 15},
+		{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15},
+	}
+)
+
+// md4Compress : one 64 byte block into the 16 byte state
+func md4Compress(state, block []byte) {
+	var x [16]uint32
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(block[4*i:])
+	}
+
+	var h [4]uint32
+	for i := range h {
+		h[i] = binary.LittleEndian.Uint32(state[4*i:])
+	}
+	a, b, c, d := h[0], h[1], h[2], h[3]
+	for r := 0; r < 3; r++ {
+		for i := 0; i < 16; i++ {
+			var f, k uint32
+			switch r {
+			case 0:
+				f = (b & c) | (^b & d)
+			case 1:
+				f, k = (b&c)|(b&d)|(c&d), 0x5A827999
+			case 2:
+				f, k = b^c^d, 0x6ED9EBA1
+			}
+			a = bits.RotateLeft32(a+f+x[md4Index[r][i]]+k, md4Shift[r][i%4])
+			a, b, c, d = d, a, b, c
+		}
+	}
+	for i, v := range [4]uint32{a, b, c, d} {
+		binary.LittleEndian.PutUint32(state[4*i:], h[i]+v)
+	}
+}
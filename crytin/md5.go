@@ -0,0 +1,66 @@
+package crytin
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// MD5 (RFC 1321)
+//
+// state a,b,c,d, 64 byte blocks, 4 rounds of 16 steps, little endian
+// Only as an MDFunc: same structure as MD4, same length extension.
+
+var (
+	md5Shift = [4][4]int{{7, 12, 17, 22}, {5, 9, 14, 20}, {4, 11, 16, 23}, {6, 10, 15, 21}}
+	// md5T : T[i] = floor(2^32 * |sin(i+1)|)
+	md5T [64]uint32
+)
+
+func init() {
+	for i := range md5T {
+		md5T[i] = uint32(math.Floor(math.Abs(math.Sin(float64(i+1))) * (1 << 32)))
+	}
+}
+
+// MD5Func : MD5 as an MDFunc
+var MD5Func = &MDFunc{
+	Name:      "MD5",
+	BlockSize: 64,
+	IV:        md4IV,
+	Compress:  md5Compress,
+	Pad:       mdPad(64, binary.LittleEndian),
+}
+
+// md5Compress : one 64 byte block into the 16 byte state
+func md5Compress(state, block []byte) {
+	var x [16]uint32
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(block[4*i:])
+	}
+	var h [4]uint32
+	for i := range h {
+		h[i] = binary.LittleEndian.Uint32(state[4*i:])
+	}
+	a, b, c, d := h[0], h[1], h[2], h[3]
+	for i := 0; i < 64; i++ {
+		var f uint32
+		var g int
+		switch i / 16 {
+		case 0:
+			f, g = (b&c)|(^b&d), i
+		case 1:
+			f, g = (b&d)|(c&^d), (5*i+1)%16
+		case 2:
+			f, g = b^c^d, (3*i+5)%16
+		case 3:
+			f, g = c^(b|^d), (7*i)%16
+		}
+		f += a + md5T[i] + x[g]
+		a, d, c = d, c, b
+		b += bits.RotateLeft32(f, md5Shift[i/16][i%4])
+	}
+	for i, v := range [4]uint32{a, b, c, d} {
+		binary.LittleEndian.PutUint32(state[4*i:], h[i]+v)
+	}
+}
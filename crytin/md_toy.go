@@ -0,0 +1,40 @@
+package crytin
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Toy AES hash (set 7)
+//
+//   state = AES(key = state padded with zeros to 16 bytes, block)[:n]
+// 16 byte blocks, an n byte state. With 16 or 24 bits of state, collisions
+// cost 2^8 or 2^12 tries, cheap enough to play with multicollisions and
+// herding. No feed forward, the state is just the truncated cipher text.
+
+// NewToyFunc : AES based toy hash with a bits wide state, bits a multiple of 8 up to 128
+func NewToyFunc(bits int) (*MDFunc, error) {
+	if bits <= 0 || bits > 8*aes.BlockSize || bits%8 != 0 {
+		return nil, fmt.Errorf("crytin: toy hash state of %d bits", bits)
+	}
+	n := bits / 8
+	iv := make([]byte, n)
+	for i := range iv {
+		iv[i] = byte(0x11 * (i + 1))
+	}
+	return &MDFunc{
+		Name:      fmt.Sprintf("toy-%d", bits),
+		BlockSize: aes.BlockSize,
+		IV:        iv,
+		Compress: func(state, block []byte) {
+			key := make([]byte, aes.BlockSize)
+			copy(key, state)
+			c, _ := aes.NewCipher(key)
+			out := make([]byte, aes.BlockSize)
+			c.Encrypt(out, block)
+			copy(state, out)
+		},
+		Pad: mdPad(aes.BlockSize, binary.BigEndian),
+	}, nil
+}
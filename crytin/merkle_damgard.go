@@ -0,0 +1,162 @@
+package crytin
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Merkle-Damgard hashes
+//
+//   state = IV
+//   state = compress(state, block) for every block of message || padding
+//   digest = state
+//
+// SHA-1, SHA-256, MD4 and MD5 only differ in the compression function,
+// the state size and the byte order of the padding. MDFunc describes one,
+// MDHash runs it. Attacks on the structure (length extension, and in set 7
+// multicollisions and herding) work on any MDFunc.
+//
+// Padding: message || 0x80 || zeros || bit length (8 bytes), whole blocks
+// SHA-1 and SHA-256 write the length big endian, MD4 and MD5 little endian.
+// It only depends on the message length, so anyone who knows the length
+// can rebuild it: the "glue" of a length extension.
+
+var (
+	// ErrHashState : injected state must sit on a block boundary
+	ErrHashState = errors.New("crytin: hash state length must be a multiple of the block size")
+	// ErrDigestSize : digest does not match the hash
+	ErrDigestSize = errors.New("crytin: wrong digest size")
+)
+
+// MDPadding : padding for a message of length bytes
+func MDPadding(length uint64, blockSize int, order binary.ByteOrder) []byte {
+	bs := uint64(blockSize)
+	zeros := (bs - (length+9)%bs) % bs
+	pad := make([]byte, 9+zeros)
+	pad[0] = 0x80
+	order.PutUint64(pad[len(pad)-8:], length*8)
+	return pad
+}
+
+// MDFunc : a Merkle-Damgard hash function
+type MDFunc struct {
+	Name      string
+	BlockSize int
+	// IV : initial state, its length is the state and digest size
+	IV []byte
+	// Compress : folds one block into state, in place
+	Compress func(state, block []byte)
+	// Pad : padding for a message of length bytes, ends on a block boundary
+	Pad func(length uint64) []byte
+}
+
+// mdPad : MDPadding for blockSize and order
+func mdPad(blockSize int, order binary.ByteOrder) func(uint64) []byte {
+	return func(length uint64) []byte { return MDPadding(length, blockSize, order) }
+}
+
+// New : running hash from the IV
+func (f *MDFunc) New() *MDHash {
+	h := &MDHash{f: f}
+	h.Reset()
+	return h
+}
+
+// Sum : digest of b
+func (f *MDFunc) Sum(b []byte) []byte {
+	h := f.New()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// MAC : secret prefix MAC, H(key || message)
+func (f *MDFunc) MAC(key, message []byte) []byte {
+	h := f.New()
+	h.Write(key)
+	h.Write(message)
+	return h.Sum(nil)
+}
+
+// Extend : length extension, message || glue || extra and its digest
+// for a secret prefix of prefixLen bytes, digest = H(prefix || message)
+func (f *MDFunc) Extend(message, digest, extra []byte, prefixLen int) (forged, forgedDigest []byte, err error) {
+	glue := f.Pad(uint64(prefixLen + len(message)))
+
+	h := f.New()
+	if err := h.SetState(digest, uint64(prefixLen+len(message)+len(glue))); err != nil {
+		return nil, nil, err
+	}
+	h.Write(extra)
+
+	forged = make([]byte, 0, len(message)+len(glue)+len(extra))
+	forged = append(append(append(forged, message...), glue...), extra...)
+	return forged, h.Sum(nil), nil
+}
+
+// MDHash : running hash of an MDFunc with injectable state, implements hash.Hash
+type MDHash struct {
+	f      *MDFunc
+	state  []byte
+	buf    []byte // partial block
+	length uint64 // bytes written, including injected ones
+}
+
+// SetState : continue from state as if length bytes were hashed already
+// length must be a multiple of the block size (padding included)
+func (h *MDHash) SetState(state []byte, length uint64) error {
+	if len(state) != len(h.f.IV) {
+		return ErrDigestSize
+	}
+	if length%uint64(h.f.BlockSize) != 0 {
+		return ErrHashState
+	}
+	h.state = append(h.state[:0], state...)
+	h.buf = h.buf[:0]
+	h.length = length
+	return nil
+}
+
+// Reset : back to the IV
+func (h *MDHash) Reset() {
+	h.state = append(h.state[:0], h.f.IV...)
+	h.buf = h.buf[:0]
+	h.length = 0
+}
+
+// Size : digest size
+func (h *MDHash) Size() int { return len(h.f.IV) }
+
+// BlockSize : block size
+func (h *MDHash) BlockSize() int { return h.f.BlockSize }
+
+// Write : hashes p, never fails
+func (h *MDHash) Write(p []byte) (int, error) {
+	n, bs := len(p), h.f.BlockSize
+	h.length += uint64(n)
+	if len(h.buf) > 0 {
+		c := bs - len(h.buf)
+		if c > len(p) {
+			c = len(p)
+		}
+		h.buf = append(h.buf, p[:c]...)
+		p = p[c:]
+		if len(h.buf) < bs {
+			return n, nil
+		}
+		h.f.Compress(h.state, h.buf)
+		h.buf = h.buf[:0]
+	}
+	for len(p) >= bs {
+		h.f.Compress(h.state, p[:bs])
+		p = p[bs:]
+	}
+	h.buf = append(h.buf, p...)
+	return n, nil
+}
+
+// Sum : appends the digest to b, h is left as it was
+func (h *MDHash) Sum(b []byte) []byte {
+	c := MDHash{f: h.f, state: append([]byte{}, h.state...), buf: append([]byte{}, h.buf...), length: h.length}
+	c.Write(h.f.Pad(h.length))
+	return append(b, c.state...)
+}
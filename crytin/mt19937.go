@@ -0,0 +1,242 @@
+package crytin
+
+// MT19937 Mersenne Twister
+//
+// 624 words of state, every 624 outputs the whole state is "twisted":
+//   y     = (mt[i] & upper) | (mt[i+1] & lower)
+//   mt[i] = mt[i+397] XOR (y >> 1) XOR (y odd ? a : 0)
+// each output is one state word "tempered" by shifts and masks.
+//
+// Not a CSPRNG: tempering is invertible (see Untemper), 624 outputs give
+// the whole state and with it every future output.
+
+const (
+	mtN         = 624
+	mtM         = 397
+	mtMatrixA   = 0x9908b0df
+	mtUpperMask = 0x80000000
+	mtLowerMask = 0x7fffffff
+)
+
+// MT19937 : 32-bit Mersenne Twister
+// Uint32 gives the outputs, Read serves them as little-endian bytes (io.Reader)
+type MT19937 struct {
+	mt    [mtN]uint32
+	index int
+	buf   [4]byte // bytes of the last output not read yet
+	nbuf  int
+}
+
+// NewMT19937 : generator seeded with seed (init_genrand)
+func NewMT19937(seed uint32) *MT19937 {
+	m := &MT19937{}
+	m.Seed(seed)
+	return m
+}
+
+// Seed : init_genrand
+func (m *MT19937) Seed(seed uint32) {
+	m.mt[0] = seed
+	for i := 1; i < mtN; i++ {
+		m.mt[i] = 1812433253*(m.mt[i-1]^(m.mt[i-1]>>30)) + uint32(i)
+	}
+	m.index = mtN
+	m.nbuf = 0
+}
+
+// SeedByArray : init_by_array
+// an empty key seeds like the one word key {0}
+func (m *MT19937) SeedByArray(key []uint32) {
+	if len(key) == 0 {
+		key = []uint32{0}
+	}
+	m.Seed(19650218)
+	i, j := 1, 0
+	k := mtN
+	if len(key) > k {
+		k = len(key)
+	}
+	for ; k > 0; k-- {
+		m.mt[i] = (m.mt[i] ^ ((m.mt[i-1] ^ (m.mt[i-1] >> 30)) * 1664525)) + key[j] + uint32(j)
+		i++
+		j++
+		if i >= mtN {
+			m.mt[0] = m.mt[mtN-1]
+			i = 1
+		}
+		if j >= len(key) {
+			j = 0
+		}
+	}
+	for k = mtN - 1; k > 0; k-- {
+		m.mt[i] = (m.mt[i] ^ ((m.mt[i-1] ^ (m.mt[i-1] >> 30)) * 1566083941)) - uint32(i)
+		i++
+		if i >= mtN {
+			m.mt[0] = m.mt[mtN-1]
+			i = 1
+		}
+	}
+	m.mt[0] = 0x80000000 // MSB is 1, assuring non-zero initial array
+}
+
+// twist : next 624 words of state
+func (m *MT19937) twist() {
+	for i := 0; i < mtN; i++ {
+		y := (m.mt[i] & mtUpperMask) | (m.mt[(i+1)%mtN] & mtLowerMask)
+		v := m.mt[(i+mtM)%mtN] ^ (y >> 1)
+		if y&1 != 0 {
+			v ^= mtMatrixA
+		}
+		m.mt[i] = v
+	}
+	m.index = 0
+}
+
+// Temper : MT19937 output transform of a state word
+func Temper(y uint32) uint32 {
+	y ^= y >> 11
+	y ^= (y << 7) & 0x9d2c5680
+	y ^= (y << 15) & 0xefc60000
+	y ^= y >> 18
+	return y
+}
+
+// Uint32 : next output (genrand_int32)
+func (m *MT19937) Uint32() uint32 {
+	if m.index >= mtN {
+		m.twist()
+	}
+	y := m.mt[m.index]
+	m.index++
+	return Temper(y)
+}
+
+// Read : fills p with outputs as little-endian bytes, never fails
+func (m *MT19937) Read(p []byte) (int, error) {
+	for i := range p {
+		if m.nbuf == 0 {
+			v := m.Uint32()
+			m.buf = [4]byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}
+			m.nbuf = 4
+		}
+		p[i] = m.buf[4-m.nbuf]
+		m.nbuf--
+	}
+	return len(p), nil
+}
+
+// MT19937-64
+//
+// Same design on 312 words of 64 bits with its own constants.
+
+const (
+	mt64N         = 312
+	mt64M         = 156
+	mt64MatrixA   = 0xB5026F5AA96619E9
+	mt64UpperMask = 0xFFFFFFFF80000000
+	mt64LowerMask = 0x7FFFFFFF
+)
+
+// MT19937x64 : 64-bit Mersenne Twister (MT19937-64)
+// Uint64 gives the outputs, Read serves them as little-endian bytes (io.Reader)
+type MT19937x64 struct {
+	mt    [mt64N]uint64
+	index int
+	buf   [8]byte
+	nbuf  int
+}
+
+// NewMT19937x64 : generator seeded with seed (init_genrand64)
+func NewMT19937x64(seed uint64) *MT19937x64 {
+	m := &MT19937x64{}
+	m.Seed(seed)
+	return m
+}
+
+// Seed : init_genrand64
+func (m *MT19937x64) Seed(seed uint64) {
+	m.mt[0] = seed
+	for i := 1; i < mt64N; i++ {
+		m.mt[i] = 6364136223846793005*(m.mt[i-1]^(m.mt[i-1]>>62)) + uint64(i)
+	}
+	m.index = mt64N
+	m.nbuf = 0
+}
+
+// SeedByArray : init_by_array64
+// an empty key seeds like the one word key {0}
+func (m *MT19937x64) SeedByArray(key []uint64) {
+	if len(key) == 0 {
+		key = []uint64{0}
+	}
+	m.Seed(19650218)
+	i, j := 1, 0
+	k := mt64N
+	if len(key) > k {
+		k = len(key)
+	}
+	for ; k > 0; k-- {
+		m.mt[i] = (m.mt[i] ^ ((m.mt[i-1] ^ (m.mt[i-1] >> 62)) * 3935559000370003845)) + key[j] + uint64(j)
+		i++
+		j++
+		if i >= mt64N {
+			m.mt[0] = m.mt[mt64N-1]
+			i = 1
+		}
+		if j >= len(key) {
+			j = 0
+		}
+	}
+	for k = mt64N - 1; k > 0; k-- {
+		m.mt[i] = (m.mt[i] ^ ((m.mt[i-1] ^ (m.mt[i-1] >> 62)) * 2862933555777941757)) - uint64(i)
+		i++
+		if i >= mt64N {
+			m.mt[0] = m.mt[mt64N-1]
+			i = 1
+		}
+	}
+	m.mt[0] = 1 << 63
+}
+
+func (m *MT19937x64) twist() {
+	for i := 0; i < mt64N; i++ {
+		x := (m.mt[i] & mt64UpperMask) | (m.mt[(i+1)%mt64N] & mt64LowerMask)
+		v := m.mt[(i+mt64M)%mt64N] ^ (x >> 1)
+		if x&1 != 0 {
+			v ^= mt64MatrixA
+		}
+		m.mt[i] = v
+	}
+	m.index = 0
+}
+
+// Uint64 : next output (genrand64_int64)
+func (m *MT19937x64) Uint64() uint64 {
+	if m.index >= mt64N {
+		m.twist()
+	}
+	x := m.mt[m.index]
+	m.index++
+
+	x ^= (x >> 29) & 0x5555555555555555
+	x ^= (x << 17) & 0x71D67FFFEDA60000
+	x ^= (x << 37) & 0xFFF7EEE000000000
+	x ^= x >> 43
+	return x
+}
+
+// Read : fills p with outputs as little-endian bytes, never fails
+func (m *MT19937x64) Read(p []byte) (int, error) {
+	for i := range p {
+		if m.nbuf == 0 {
+			v := m.Uint64()
+			for j := range m.buf {
+				m.buf[j] = byte(v >> (8 * uint(j)))
+			}
+			m.nbuf = 8
+		}
+		p[i] = m.buf[8-m.nbuf]
+		m.nbuf--
+	}
+	return len(p), nil
+}
@@ -0,0 +1,58 @@
+package crytin
+
+import (
+	"bytes"
+	"time"
+)
+
+//MT19937 stream cipher
+//
+// key stream = MT19937(seed) outputs as little-endian bytes
+// cb = pb XOR key stream, encrypt and decrypt are the same
+//
+// A 16-bit seed is 65536 keys: with a few known plain text bytes anywhere
+// in the message, try them all.
+//
+// The same generator seeded with the time makes "password reset tokens"
+// that are as guessable as the clock.
+
+// MT19937Cipher : XOR with the MT19937 key stream of a 16-bit seed
+func MT19937Cipher(b []byte, seed uint16) []byte {
+	ks := make([]byte, len(b))
+	NewMT19937(uint32(seed)).Read(ks)
+	return XOR(b, ks)
+}
+
+// AttackMT19937Cipher : seed of a cipher text whose plain text ends in known
+// false if known is empty (nothing to check) or longer than cb
+func AttackMT19937Cipher(cb, known []byte) (uint16, bool) {
+	if len(known) == 0 || len(known) > len(cb) {
+		return 0, false
+	}
+	tail := XOR(cb[len(cb)-len(known):], known) // key stream tail
+	ks := make([]byte, len(cb))
+	for seed := 0; seed <= 0xffff; seed++ {
+		NewMT19937(uint32(seed)).Read(ks)
+		if bytes.Equal(ks[len(cb)-len(known):], tail) {
+			return uint16(seed), true
+		}
+	}
+	return 0, false
+}
+
+// MT19937Token : n byte token from MT19937 seeded with seed
+func MT19937Token(seed uint32, n int) []byte {
+	token := make([]byte, n)
+	NewMT19937(seed).Read(token)
+	return token
+}
+
+// DetectMT19937TimeToken : did token come from MT19937 seeded with a unix time
+// within window before (or after, for clock skew) now
+// returns the seed when it did
+func DetectMT19937TimeToken(token []byte, now time.Time, window time.Duration) (uint32, bool) {
+	seed, ok := BruteForceSeed(now.Add(-window).Unix(), now.Add(window).Unix(), func(seed int64) bool {
+		return bytes.Equal(MT19937Token(uint32(seed), len(token)), token)
+	})
+	return uint32(seed), ok
+}
@@ -0,0 +1,219 @@
+// Package netsim : in-process network for protocol attacks
+//
+//   A --Send--> [Adversary] --deliver--> B.Recv
+//
+// Parties are goroutines holding an Endpoint. Every message goes through
+// the network's Adversary, which sees it and decides what gets delivered:
+// the message itself, nothing (drop), a changed copy (rewrite), the
+// message plus an older one (replay) or messages to anyone else.
+// Every send is recorded in the transcript with what was delivered.
+//
+// No sockets, no timing: MITM attacks (set 5: 34, 35, 37) become plain tests.
+package netsim
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Errors returned by Send and Recv
+var (
+	ErrUnknownParty = errors.New("netsim: unknown party")
+	ErrTimeout      = errors.New("netsim: timed out waiting for a message")
+	ErrUnexpected   = errors.New("netsim: unexpected message type")
+	ErrInboxFull    = errors.New("netsim: timed out delivering to a full inbox")
+)
+
+// DefaultTimeout : how long Recv waits and Send waits on a full inbox,
+// a dropped or flooded message must not hang a test
+const DefaultTimeout = 2 * time.Second
+
+// inboxSize : messages a party can have waiting
+const inboxSize = 64
+
+// Message : typed message between two parties
+// Payload is shared, not copied: an adversary rewriting it must copy first
+type Message struct {
+	From, To string
+	Type     string
+	Payload  interface{}
+}
+
+func (m Message) String() string {
+	return fmt.Sprintf("%s -> %s %s: %v", m.From, m.To, m.Type, m.Payload)
+}
+
+// Adversary : sits on the wire, returns the messages to deliver instead of m
+//   []Message{m}       pass
+//   nil                drop
+//   []Message{m2}      rewrite (m2 a changed copy)
+//   []Message{m, old}  replay
+// Calls are serialized, outside the network's lock: Intercept may read the
+// Transcript, but must not Send, it returns the messages to deliver instead.
+type Adversary interface {
+	Intercept(m Message) []Message
+}
+
+// AdversaryFunc : function as an Adversary
+type AdversaryFunc func(m Message) []Message
+
+// Intercept : calls f
+func (f AdversaryFunc) Intercept(m Message) []Message { return f(m) }
+
+// Passive : delivers everything, the transcript is the eavesdropper's view
+var Passive = AdversaryFunc(func(m Message) []Message { return []Message{m} })
+
+// Record : one send in the transcript
+type Record struct {
+	Sent      Message
+	Delivered []Message
+}
+
+// Network : parties, the adversary and the transcript
+type Network struct {
+	Timeout time.Duration // Recv and delivery timeout, DefaultTimeout when 0
+
+	wire       sync.Mutex // one send at a time: Intercept, transcript, delivery
+	adv        Adversary
+	mu         sync.Mutex
+	inboxes    map[string]chan Message
+	transcript []Record
+}
+
+// New : network with adv on the wire, nil for Passive
+func New(adv Adversary) *Network {
+	if adv == nil {
+		adv = Passive
+	}
+	return &Network{adv: adv, inboxes: map[string]chan Message{}}
+}
+
+// Join : endpoint for party name
+func (n *Network) Join(name string) *Endpoint {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	inbox, ok := n.inboxes[name]
+	if !ok {
+		inbox = make(chan Message, inboxSize)
+		n.inboxes[name] = inbox
+	}
+	return &Endpoint{name: name, net: n, inbox: inbox}
+}
+
+// Transcript : every send so far, in order
+func (n *Network) Transcript() []Record {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Record{}, n.transcript...)
+}
+
+// Delivered : every delivered message so far, in order
+func (n *Network) Delivered() []Message {
+	var ms []Message
+	for _, r := range n.Transcript() {
+		ms = append(ms, r.Delivered...)
+	}
+	return ms
+}
+
+// timeout : Timeout or DefaultTimeout
+func (n *Network) timeout() time.Duration {
+	if n.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return n.Timeout
+}
+
+// send : passes m through the adversary and delivers the outcome
+// ErrInboxFull when a recipient does not make room in time
+// The wire is held until delivery so concurrent sends are seen, recorded
+// and delivered in the same order, a full inbox holds up other senders.
+func (n *Network) send(m Message) error {
+	n.wire.Lock()
+	defer n.wire.Unlock()
+	out := n.adv.Intercept(m)
+
+	n.mu.Lock()
+	n.transcript = append(n.transcript, Record{Sent: m, Delivered: out})
+	inboxes := make([]chan Message, len(out))
+	for i, d := range out {
+		inbox, ok := n.inboxes[d.To]
+		if !ok {
+			n.mu.Unlock()
+			return fmt.Errorf("%w: %q", ErrUnknownParty, d.To)
+		}
+		inboxes[i] = inbox
+	}
+	n.mu.Unlock()
+
+	timer := time.NewTimer(n.timeout())
+	defer timer.Stop()
+	for i, d := range out {
+		select {
+		case inboxes[i] <- d:
+		case <-timer.C:
+			return fmt.Errorf("%w: %s", ErrInboxFull, d.To)
+		}
+	}
+	return nil
+}
+
+// Endpoint : a party's connection to the network
+type Endpoint struct {
+	name  string
+	net   *Network
+	inbox chan Message
+}
+
+// Name : the party's name
+func (e *Endpoint) Name() string { return e.name }
+
+// Send : sends a message of type typ to party to
+func (e *Endpoint) Send(to, typ string, payload interface{}) error {
+	return e.net.send(Message{From: e.name, To: to, Type: typ, Payload: payload})
+}
+
+// Recv : next message, ErrTimeout when none arrives in time
+func (e *Endpoint) Recv() (Message, error) {
+	select {
+	case m := <-e.inbox:
+		return m, nil
+	case <-time.After(e.net.timeout()):
+		return Message{}, fmt.Errorf("%w: %s", ErrTimeout, e.name)
+	}
+}
+
+// Expect : next message, which must be of type typ
+func (e *Endpoint) Expect(typ string) (Message, error) {
+	m, err := e.Recv()
+	if err != nil {
+		return m, err
+	}
+	if m.Type != typ {
+		return m, fmt.Errorf("%w: %s wanted %q, got %q", ErrUnexpected, e.name, typ, m.Type)
+	}
+	return m, nil
+}
+
+// Run : runs every party in its own goroutine, waits for all of them and
+// returns the first error
+func Run(parties ...func() error) error {
+	errs := make([]error, len(parties))
+	var wg sync.WaitGroup
+	for i, p := range parties {
+		wg.Add(1)
+		go func(i int, p func() error) {
+			defer wg.Done()
+			errs[i] = p()
+		}(i, p)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
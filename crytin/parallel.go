@@ -0,0 +1,117 @@
+package crytin
+
+import (
+	"crypto/cipher"
+	"runtime"
+	"sync"
+)
+
+// Parallel modes
+//
+// ECB blocks are independent both ways.
+// CBC decryption only needs cb[i-1], cb[i] and the key for pb[i]:
+//   pb[i] = dec(cb[i]) XOR cb[i-1]
+// so the cipher text can be cut into chunks decrypted side by side.
+// CBC encryption chains on the previous cipher block, it stays serial.
+//
+// Output is identical to the serial DecryptAesCbc, EncryptAesEcb, DecryptAesEcb.
+
+// parallelBlocks : runs fn over [from, to) block ranges of n blocks
+// workers <= 0 uses one worker per CPU
+func parallelBlocks(n, workers int, fn func(from, to int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+
+	var wg sync.WaitGroup
+	chunk := (n + workers - 1) / workers
+	for from := 0; from < n; from += chunk {
+		to := from + chunk
+		if to > n {
+			to = n
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			fn(from, to)
+		}(from, to)
+	}
+	wg.Wait()
+}
+
+// ecbParallel : ECB over the whole blocks of src
+func ecbParallel(crypt func(dst, src []byte), bs int, dst, src []byte, workers int) {
+	parallelBlocks(len(src)/bs, workers, func(from, to int) {
+		for i := from * bs; i < to*bs; i += bs {
+			crypt(dst[i:i+bs], src[i:i+bs])
+		}
+	})
+}
+
+// EncryptAesEcbParallel : EncryptAesEcb over workers goroutines
+func EncryptAesEcbParallel(pb, key []byte, workers int) ([]byte, error) {
+	c, err := newAesCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	PKCS7PadStrict(&pb, uint(c.BlockSize()))
+	cb := make([]byte, len(pb))
+	ecbParallel(c.Encrypt, c.BlockSize(), cb, pb, workers)
+	return cb, nil
+}
+
+// DecryptAesEcbParallel : DecryptAesEcb over workers goroutines
+func DecryptAesEcbParallel(cb, key []byte, workers int) ([]byte, error) {
+	c, err := newAesCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	pb := make([]byte, len(cb))
+	ecbParallel(c.Decrypt, c.BlockSize(), pb, cb, workers)
+	RemovePadding(&pb)
+	return pb, nil
+}
+
+// cbcDecryptBlocks : pb[from:to] blocks, XOR done in place (no allocation)
+func cbcDecryptBlocks(c cipher.Block, pb, cb, iv []byte, from, to int) {
+	bs := c.BlockSize()
+	for i := from * bs; i < to*bs; i += bs {
+		prev := iv[:bs]
+		if i > 0 {
+			prev = cb[i-bs : i]
+		}
+		c.Decrypt(pb[i:i+bs], cb[i:i+bs])
+		for j := 0; j < bs; j++ {
+			pb[i+j] ^= prev[j]
+		}
+	}
+}
+
+// DecryptAesCbcParallel : DecryptAesCbc over workers goroutines
+func DecryptAesCbcParallel(cb, key, iv []byte, workers int) ([]byte, error) {
+	c, err := newAesCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckCBCIV(key, iv); err != nil {
+		return nil, err
+	}
+	bs := c.BlockSize()
+	if len(iv) < bs {
+		return nil, ErrBlockSize
+	}
+
+	pb := make([]byte, len(cb))
+	parallelBlocks(len(cb)/bs, workers, func(from, to int) {
+		cbcDecryptBlocks(c, pb, cb, iv, from, to)
+	})
+	RemovePadding(&pb)
+	return pb, nil
+}
@@ -0,0 +1,53 @@
+package crytin
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+//Randomness
+//
+// Keys, IVs, nonces and every oracle's secrets are read from an io.Reader:
+//   nil            : crypto/rand, unpredictable
+//   NewSeededReader : deterministic, replays an attack bit for bit
+// Never math/rand seeded with the time, see RecoverMathRandSeed.
+
+// Sizes of generated secrets
+const (
+	KeySize   = 16 // AES-128
+	IVSize    = 16 // one AES block
+	NonceSize = 8  // CTR nonce, half a block
+)
+
+// RandomBytes : n bytes read from r, crypto/rand when r is nil
+func RandomBytes(r io.Reader, n int) ([]byte, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// RandomKey : random AES-128 key
+func RandomKey(r io.Reader) ([]byte, error) {
+	return RandomBytes(r, KeySize)
+}
+
+// RandomIV : random CBC IV
+func RandomIV(r io.Reader) ([]byte, error) {
+	return RandomBytes(r, IVSize)
+}
+
+// RandomNonce : random CTR nonce
+func RandomNonce(r io.Reader) ([]byte, error) {
+	return RandomBytes(r, NonceSize)
+}
+
+// NewSeededReader : deterministic random bytes (MT19937 key stream)
+// for tests and replays only, it is predictable by design
+func NewSeededReader(seed uint32) io.Reader {
+	return NewMT19937(seed)
+}
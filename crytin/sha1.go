@@ -0,0 +1,109 @@
+package crytin
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// SHA-1
+//
+// state h0..h4, 64 byte blocks, 80 rounds per block, big endian
+// The digest is the state after the last (padding) block. Set the state
+// from a digest (SetState) and hashing carries on from where it stopped.
+
+const (
+	// SHA1Size : digest size
+	SHA1Size = 20
+	// SHA1BlockSize : block size
+	SHA1BlockSize = 64
+)
+
+// SHA1Func : SHA-1 as an MDFunc
+var SHA1Func = &MDFunc{
+	Name:      "SHA-1",
+	BlockSize: SHA1BlockSize,
+	IV: []byte{
+		0x67, 0x45, 0x23, 0x01, 0xef, 0xcd, 0xab, 0x89, 0x98, 0xba,
+		0xdc, 0xfe, 0x10, 0x32, 0x54, 0x76, 0xc3, 0xd2, 0xe1, 0xf0,
+	},
+	Compress: sha1Compress,
+	Pad:      mdPad(SHA1BlockSize, binary.BigEndian),
+}
+
+// SHA1 : SHA-1 with injectable state, implements hash.Hash
+// a thin wrapper over MDHash, the state as h0..h4
+type SHA1 struct {
+	*MDHash
+}
+
+// NewSHA1 : SHA-1 from the standard initial state
+func NewSHA1() *SHA1 {
+	return &SHA1{SHA1Func.New()}
+}
+
+// SetState : continue from h as if length bytes were hashed already
+// length must be a multiple of the block size (padding included)
+func (d *SHA1) SetState(h [5]uint32, length uint64) error {
+	return d.MDHash.SetState(sha1StateBytes(h), length)
+}
+
+// SHA1State : h0..h4 held in a digest
+func SHA1State(digest []byte) ([5]uint32, error) {
+	var h [5]uint32
+	if len(digest) != SHA1Size {
+		return h, ErrDigestSize
+	}
+	for i := range h {
+		h[i] = binary.BigEndian.Uint32(digest[4*i:])
+	}
+	return h, nil
+}
+
+// sha1StateBytes : digest of state words
+func sha1StateBytes(h [5]uint32) []byte {
+	b := make([]byte, 4*len(h))
+	for i, v := range h {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// SHA1Sum : SHA-1 digest of b
+func SHA1Sum(b []byte) []byte {
+	return SHA1Func.Sum(b)
+}
+
+// sha1Compress : one 64 byte block into the 20 byte state
+func sha1Compress(state, block []byte) {
+	var w [80]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(block[4*i:])
+	}
+	for i := 16; i < 80; i++ {
+		w[i] = bits.RotateLeft32(w[i-3]^w[i-8]^w[i-14]^w[i-16], 1)
+	}
+
+	var h [5]uint32
+	for i := range h {
+		h[i] = binary.BigEndian.Uint32(state[4*i:])
+	}
+	a, b, c, d, e := h[0], h[1], h[2], h[3], h[4]
+	for i := 0; i < 80; i++ {
+		var f, k uint32
+		switch {
+		case i < 20:
+			f, k = (b&c)|(^b&d), 0x5A827999
+		case i < 40:
+			f, k = b^c^d, 0x6ED9EBA1
+		case i < 60:
+			f, k = (b&c)|(b&d)|(c&d), 0x8F1BBCDC
+		default:
+			f, k = b^c^d, 0xCA62C1D6
+		}
+		t := bits.RotateLeft32(a, 5) + f + e + k + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	for i, v := range [5]uint32{a, b, c, d, e} {
+		binary.BigEndian.PutUint32(state[4*i:], h[i]+v)
+	}
+}
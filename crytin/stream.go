@@ -0,0 +1,246 @@
+package crytin
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+)
+
+// Streaming encryption
+//
+// The []byte modes need the whole message in memory. The stream wrappers
+// work a block at a time:
+//   EncryptWriter : encrypts whole blocks as they fill, pads only on Close
+//   DecryptReader : decrypts whole blocks as they arrive but always holds the
+//                   last block back, at EOF it is the padding block and is
+//                   strictly validated before it is released
+// CTR has no padding, both wrappers pass bytes straight through the key stream.
+//
+// Padding is strict PKCS7 (PKCS7PadStrict / PKCS7Unpad): a full block of
+// padding is added when the message fills its last block. The []byte modes
+// pad the same way, so either side reads the other's cipher text.
+
+// Mode : block cipher mode of a stream wrapper
+type Mode int
+
+// Stream wrapper modes
+const (
+	ModeECB Mode = iota // iv unused
+	ModeCBC             // iv is the 16 byte CBC IV
+	ModeCTR             // iv is the 8 byte CTR nonce
+)
+
+// ErrMode : unknown mode
+var ErrMode = errors.New("crytin: unknown mode")
+
+// streamSize : bytes read from the source per refill
+const streamSize = 32 * 1024
+
+// blockStream : mode state carried across blocks
+type blockStream struct {
+	mode   Mode
+	c      cipher.Block
+	iv     []byte // CBC previous cipher block
+	ctr    *CtrStream
+	offset int64 // CTR position
+}
+
+func newBlockStream(mode Mode, key, iv []byte) (*blockStream, error) {
+	s := &blockStream{mode: mode}
+	var err error
+	switch mode {
+	case ModeECB:
+		s.c, err = newAesCipher(key)
+	case ModeCBC:
+		if err := CheckCBCIV(key, iv); err != nil {
+			return nil, err
+		}
+		if s.c, err = newAesCipher(key); err == nil && len(iv) != s.c.BlockSize() {
+			err = ErrBlockSize
+		}
+		s.iv = append([]byte{}, iv...)
+	case ModeCTR:
+		s.ctr, err = NewAesCtr(key, iv)
+	default:
+		err = ErrMode
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// blockSize : 1 for CTR, nothing is held back
+func (s *blockStream) blockSize() int {
+	if s.mode == ModeCTR {
+		return 1
+	}
+	return s.c.BlockSize()
+}
+
+// crypt : encrypts or decrypts src into dst, src is whole blocks
+func (s *blockStream) crypt(dst, src []byte, encrypt bool) {
+	switch s.mode {
+	case ModeECB:
+		bs := s.c.BlockSize()
+		for i := 0; i+bs <= len(src); i += bs {
+			if encrypt {
+				s.c.Encrypt(dst[i:i+bs], src[i:i+bs])
+			} else {
+				s.c.Decrypt(dst[i:i+bs], src[i:i+bs])
+			}
+		}
+	case ModeCBC:
+		if len(src) == 0 {
+			return
+		}
+		bs := s.c.BlockSize()
+		if encrypt {
+			copy(dst, cbcEncrypt(s.c, src, s.iv))
+			copy(s.iv, dst[len(src)-bs:len(src)])
+		} else {
+			last := append([]byte{}, src[len(src)-bs:]...)
+			copy(dst, cbcDecrypt(s.c, src, s.iv))
+			copy(s.iv, last)
+		}
+	case ModeCTR:
+		s.ctr.XORKeyStreamAt(dst, src, s.offset) // offset only grows from 0
+		s.offset += int64(len(src))
+	}
+}
+
+// EncryptWriter : encrypting io.WriteCloser
+type EncryptWriter struct {
+	w      io.Writer
+	s      *blockStream
+	buf    []byte // partial block waiting for more input
+	closed bool
+}
+
+// NewEncryptWriter : writes the encryption of everything written to w
+// Close must be called to write the final (padding) block,
+// it does not close w
+func NewEncryptWriter(w io.Writer, mode Mode, key, iv []byte) (*EncryptWriter, error) {
+	s, err := newBlockStream(mode, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptWriter{w: w, s: s}, nil
+}
+
+// Write : encrypts and writes all whole blocks, keeps the rest
+func (e *EncryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("crytin: write to closed EncryptWriter")
+	}
+	e.buf = append(e.buf, p...)
+	bs := e.s.blockSize()
+	n := len(e.buf) / bs * bs
+	if n == 0 {
+		return len(p), nil
+	}
+	out := make([]byte, n)
+	e.s.crypt(out, e.buf[:n], true)
+	e.buf = append(e.buf[:0], e.buf[n:]...)
+	if _, err := e.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close : pads and writes the last block
+func (e *EncryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	if e.s.mode == ModeCTR {
+		return nil
+	}
+	PKCS7PadStrict(&e.buf, uint(e.s.blockSize()))
+	out := make([]byte, len(e.buf))
+	e.s.crypt(out, e.buf, true)
+	_, err := e.w.Write(out)
+	return err
+}
+
+// DecryptReader : decrypting io.Reader
+type DecryptReader struct {
+	r   io.Reader
+	s   *blockStream
+	in  []byte // cipher text not decrypted yet, holds the last block back
+	out []byte // plain text ready to be read
+	eof bool
+	err error
+}
+
+// NewDecryptReader : reads the decryption of r
+// a bad final padding or a partial final block is returned as an error at EOF
+func NewDecryptReader(r io.Reader, mode Mode, key, iv []byte) (*DecryptReader, error) {
+	s, err := newBlockStream(mode, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptReader{r: r, s: s}, nil
+}
+
+// fill : reads from r and decrypts what is safe to release
+func (d *DecryptReader) fill() {
+	chunk := make([]byte, streamSize)
+	n, err := d.r.Read(chunk)
+	d.in = append(d.in, chunk[:n]...)
+	if err == io.EOF {
+		d.eof = true
+	} else if err != nil {
+		d.err = err
+		return
+	}
+
+	bs := d.s.blockSize()
+	if d.s.mode == ModeCTR {
+		d.out = make([]byte, len(d.in))
+		d.s.crypt(d.out, d.in, false)
+		d.in = d.in[:0]
+		return
+	}
+
+	if !d.eof {
+		// hold back at least one byte, so the last whole block stays in d.in
+		n := 0
+		if len(d.in) > 0 {
+			n = (len(d.in) - 1) / bs * bs
+		}
+		d.out = make([]byte, n)
+		d.s.crypt(d.out, d.in[:n], false)
+		d.in = append(d.in[:0], d.in[n:]...)
+		return
+	}
+
+	if len(d.in) == 0 || len(d.in)%bs != 0 {
+		d.err = ErrBlockSize
+		return
+	}
+	d.out = make([]byte, len(d.in))
+	d.s.crypt(d.out, d.in, false)
+	d.in = d.in[:0]
+	if err := PKCS7Unpad(&d.out, uint(bs)); err != nil {
+		d.out = nil
+		d.err = err
+	}
+}
+
+// Read : io.Reader
+func (d *DecryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.eof {
+			return 0, io.EOF
+		}
+		d.fill()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
@@ -0,0 +1,67 @@
+package crytin
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Timing leak server (challenges 31, 32)
+//
+//   GET /test?file=foo&signature=46b4ec586117154dacd49d664e5d63fdc88efb51
+// 200 when signature is HMAC-SHA1(key, file), 500 otherwise.
+// The signature is compared byte by byte with a sleep after every matching
+// byte and an early exit on the first mismatch, so the response time tells
+// how many leading bytes are right. See AttackTimingLeak.
+
+// InsecureCompare : early exit compare, sleeps delay after every matching byte
+func InsecureCompare(a, b []byte, delay time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+		time.Sleep(delay)
+	}
+	return true
+}
+
+// TimingLeakServer : http.Handler checking HMAC-SHA1 signatures with InsecureCompare
+// serve it with net/http or net/http/httptest
+type TimingLeakServer struct {
+	key     []byte
+	delay   time.Duration
+	macSize int
+}
+
+// NewTimingLeakServer : server with a key read from rnd (nil for crypto/rand),
+// delay per matching byte and the first macSize bytes of the HMAC as the
+// signature (0 for all 20)
+func NewTimingLeakServer(rnd io.Reader, delay time.Duration, macSize int) (*TimingLeakServer, error) {
+	key, err := RandomKey(rnd)
+	if err != nil {
+		return nil, err
+	}
+	if macSize <= 0 || macSize > SHA1Size {
+		macSize = SHA1Size
+	}
+	return &TimingLeakServer{key: key, delay: delay, macSize: macSize}, nil
+}
+
+// Signature : the signature the server expects for file
+func (s *TimingLeakServer) Signature(file []byte) []byte {
+	return HMACSHA1(s.key, file)[:s.macSize]
+}
+
+// ServeHTTP : checks the file and signature query parameters
+func (s *TimingLeakServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sig, err := FromHex(q.Get("signature"))
+	if err != nil || !InsecureCompare(s.Signature([]byte(q.Get("file"))), sig, s.delay) {
+		http.Error(w, "invalid signature", http.StatusInternalServerError)
+		return
+	}
+	io.WriteString(w, "ok\n")
+}
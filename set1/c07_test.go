@@ -2,7 +2,10 @@ package main
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/srinivengala/cryptopals/crytin"
@@ -39,3 +42,201 @@ func TestDecryptAESECBMode(t *testing.T) {
 	}
 
 }
+
+// testPPM : 64x64 image of 4 flat color bands
+func testPPM() []byte {
+	const w, h = 64, 64
+	img := []byte(fmt.Sprintf("P6\n# penguin\n%d %d\n255\n", w, h))
+	colors := [][]byte{{0, 0, 0}, {255, 255, 255}, {255, 128, 0}, {0, 0, 0}}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img = append(img, colors[y*len(colors)/h]...)
+		}
+	}
+	return img
+}
+
+// testBMP : 32x32 24-bit BI_RGB image of 2 flat color bands
+func testBMP() []byte {
+	const w, h, offset = 32, 32, 54
+	pixels := w * h * 3
+	img := make([]byte, offset, offset+pixels)
+	copy(img, "BM")
+	binary.LittleEndian.PutUint32(img[2:], uint32(offset+pixels))
+	binary.LittleEndian.PutUint32(img[10:], offset)
+	binary.LittleEndian.PutUint32(img[14:], 40)
+	binary.LittleEndian.PutUint32(img[18:], w)
+	binary.LittleEndian.PutUint32(img[22:], h)
+	binary.LittleEndian.PutUint16(img[26:], 1)
+	binary.LittleEndian.PutUint16(img[28:], 24)
+	for i := 0; i < pixels; i++ {
+		img = append(img, byte(i*2/pixels)*200)
+	}
+	return img
+}
+
+func TestECBPenguin(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := make([]byte, len(key))
+	ecb := func(pb []byte) ([]byte, error) { return crytin.EncryptAesEcb(pb, key) }
+	cbc := func(pb []byte) ([]byte, error) { return crytin.EncryptAesCbc(pb, key, iv) }
+
+	for name, img := range map[string][]byte{"ppm": testPPM(), "bmp": testBMP()} {
+		header, pixels, err := crytin.SplitImage(img)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+
+		ecbImg, err := crytin.EncryptImage(img, ecb)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		cbcImg, err := crytin.EncryptImage(img, cbc)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+
+		if len(ecbImg) != len(img) || !bytes.HasPrefix(ecbImg, header) {
+			t.Errorf("%s: header or size not kept", name)
+		}
+
+		ecbReport := crytin.AnalyzeECBBlockSize(ecbImg[len(header):], 16)
+		cbcReport := crytin.AnalyzeECBBlockSize(cbcImg[len(header):], 16)
+		t.Logf("%s: %d pixel bytes, ECB repeats %d, CBC repeats %d", name, len(pixels), ecbReport.Repeats, cbcReport.Repeats)
+		if ecbReport.Repeats == 0 {
+			t.Errorf("%s: ECB hid the penguin", name)
+		}
+		if cbcReport.Repeats != 0 {
+			t.Errorf("%s: CBC showed the penguin", name)
+		}
+	}
+
+	if _, err := crytin.EncryptImage([]byte("GIF89a"), ecb); err == nil {
+		t.Error("expected unsupported format error")
+	}
+}
+
+func TestECBPenguinFile(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "penguin.ppm"), filepath.Join(dir, "penguin-ecb.ppm")
+	if err := ioutil.WriteFile(in, testPPM(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key := []byte("YELLOW SUBMARINE")
+	err := crytin.EncryptImageFile(in, out, func(pb []byte) ([]byte, error) { return crytin.EncryptAesEcb(pb, key) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := crytin.SplitImage(mustRead(t, out)); err != nil {
+		t.Error(err)
+	}
+}
+
+func mustRead(t *testing.T, name string) []byte {
+	dat, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dat
+}
+
+func TestECBModeParallel(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	dat, err := ioutil.ReadFile("../data/7.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, _ := crytin.FromBase64(dat)
+
+	want, _ := crytin.DecryptAesEcb(cb, key)
+	for _, workers := range []int{0, 1, 5} {
+		pb, err := crytin.DecryptAesEcbParallel(cb, key, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pb, want) {
+			t.Errorf("%d workers: parallel ECB decrypt differs from serial", workers)
+		}
+		cb2, err := crytin.EncryptAesEcbParallel(pb, key, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(cb2, cb) {
+			t.Errorf("%d workers: parallel ECB encrypt differs from serial", workers)
+		}
+	}
+}
+
+func TestECBModeKeySizes(t *testing.T) {
+	// padding follows the 16 byte AES block whatever the key size
+	pb := []byte("twenty bytes of text")
+	for _, key := range [][]byte{[]byte("YELLOW SUBMARINE"), []byte("YELLOW SUBMARINE 24 BYTE"), []byte("YELLOW SUBMARINE, 32 BYTES KEY!!")} {
+		cb, err := crytin.EncryptAesEcb(append([]byte{}, pb...), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cb)%16 != 0 {
+			t.Errorf("%d byte key: %d bytes of cipher text", len(key), len(cb))
+		}
+		got, err := crytin.DecryptAesEcb(cb, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pb) {
+			t.Errorf("%d byte key: serial round trip gave %q", len(key), got)
+		}
+
+		pcb, err := crytin.EncryptAesEcbParallel(append([]byte{}, pb...), key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pcb, cb) {
+			t.Errorf("%d byte key: parallel ECB encrypt differs from serial", len(key))
+		}
+		got, err = crytin.DecryptAesEcbParallel(pcb, key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, pb) {
+			t.Errorf("%d byte key: parallel round trip gave %q", len(key), got)
+		}
+	}
+}
+
+// go test -bench ECB -benchmem
+
+func benchECBData() []byte {
+	return bytes.Repeat([]byte("YELLOW SUBMARINE"), 1<<18) // 4MB
+}
+
+func BenchmarkEncryptAesEcb(b *testing.B) {
+	key, pb := []byte("YELLOW SUBMARINE"), benchECBData()
+	b.SetBytes(int64(len(pb)))
+	for i := 0; i < b.N; i++ {
+		crytin.EncryptAesEcb(pb[:len(pb):len(pb)], key)
+	}
+}
+
+func BenchmarkEncryptAesEcbParallel(b *testing.B) {
+	key, pb := []byte("YELLOW SUBMARINE"), benchECBData()
+	b.SetBytes(int64(len(pb)))
+	for i := 0; i < b.N; i++ {
+		crytin.EncryptAesEcbParallel(pb[:len(pb):len(pb)], key, 0)
+	}
+}
+
+func BenchmarkDecryptAesEcb(b *testing.B) {
+	key, cb := []byte("YELLOW SUBMARINE"), benchECBData()
+	b.SetBytes(int64(len(cb)))
+	for i := 0; i < b.N; i++ {
+		crytin.DecryptAesEcb(cb, key)
+	}
+}
+
+func BenchmarkDecryptAesEcbParallel(b *testing.B) {
+	key, cb := []byte("YELLOW SUBMARINE"), benchECBData()
+	b.SetBytes(int64(len(cb)))
+	for i := 0; i < b.N; i++ {
+		crytin.DecryptAesEcbParallel(cb, key, 0)
+	}
+}
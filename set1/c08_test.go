@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/srinivengala/cryptopals/crytin"
@@ -41,3 +43,45 @@ func TestDetectECBMode(t *testing.T) {
 		t.Error("ECB not detected in ../data/8.txt")
 	}
 }
+
+func TestRankECB(t *testing.T) {
+	dat, err := ioutil.ReadFile("../data/8.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cbs := [][]byte{}
+	for _, line := range strings.Fields(string(dat)) {
+		cb, err := crytin.FromHex(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cbs = append(cbs, cb)
+	}
+
+	ranks := crytin.RankECB(cbs)
+	for _, r := range ranks[:3] {
+		t.Logf("line %d : block size %d, %d/%d repeats, score %.2f, duplicates %v",
+			r.Index+1, r.Report.BlockSize, r.Report.Repeats, r.Report.Blocks, r.Report.Score, r.Report.Duplicates)
+	}
+
+	top := ranks[0].Report
+	if top.BlockSize != 16 || top.Repeats == 0 {
+		t.Errorf("expected an AES-ECB line on top, got %+v", top)
+	}
+	if ranks[1].Report.Repeats != 0 {
+		t.Errorf("expected a single ECB line in ../data/8.txt")
+	}
+}
+
+func TestAnalyzeECB8ByteBlocks(t *testing.T) {
+	// 8-byte repeats that never line up on 16-byte blocks, DES-ECB like
+	cb := []byte("0123456789abcdefABCDEFGH0123456789abcdefXXXXXXXXYYYYYYYY")
+	best := crytin.AnalyzeECB(cb)[0]
+	if best.BlockSize != 8 || best.Repeats != 2 {
+		t.Errorf("expected 8-byte ECB, got %+v", best)
+	}
+	if isECB, bs := crytin.DetectECB(cb); !isECB || bs != 8 {
+		t.Errorf("DetectECB = %v, %d", isECB, bs)
+	}
+}
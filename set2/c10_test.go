@@ -3,7 +3,9 @@ package main
 import (
 	"bytes"
 	"io/ioutil"
+	"math/rand"
 	"testing"
+	"testing/iotest"
 
 	"github.com/srinivengala/cryptopals/crytin"
 )
@@ -65,3 +67,187 @@ func TestCBCModeFile(t *testing.T) {
 		t.Errorf("not equal")
 	}
 }
+
+// benchData : n bytes of cipher-looking data
+func benchData(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(b)
+	return b
+}
+
+func TestCBCModeParallel(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := make([]byte, len(key))
+
+	dat, err := ioutil.ReadFile("../data/10.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, _ := crytin.FromBase64(dat)
+
+	for _, in := range [][]byte{cb, benchData(1<<20 + 16)} {
+		want, err := crytin.DecryptAesCbc(in, key, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{0, 1, 3, 16} {
+			got, err := crytin.DecryptAesCbcParallel(in, key, iv, workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%d workers: parallel CBC differs from serial", workers)
+			}
+		}
+	}
+}
+
+func TestCBCModeKeySizes(t *testing.T) {
+	// padding follows the 16 byte AES block whatever the key size
+	pb := []byte("twenty bytes of text")
+	iv := make([]byte, 16)
+	for _, key := range [][]byte{[]byte("YELLOW SUBMARINE"), []byte("YELLOW SUBMARINE 24 BYTE"), []byte("YELLOW SUBMARINE, 32 BYTES KEY!!")} {
+		cb, err := crytin.EncryptAesCbc(append([]byte{}, pb...), key, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{1, 3} {
+			got, err := crytin.DecryptAesCbcParallel(cb, key, iv, workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, pb) {
+				t.Errorf("%d byte key, %d workers: round trip gave %q", len(key), workers, got)
+			}
+		}
+	}
+}
+
+func TestCBCModeShortIV(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := []byte("short")
+	if _, err := crytin.EncryptAesCbc([]byte("some text"), key, iv); err != crytin.ErrBlockSize {
+		t.Errorf("EncryptAesCbc: expected ErrBlockSize, got %v", err)
+	}
+	if _, err := crytin.DecryptAesCbc(make([]byte, 32), key, iv); err != crytin.ErrBlockSize {
+		t.Errorf("DecryptAesCbc: expected ErrBlockSize, got %v", err)
+	}
+}
+
+// go test -bench CBC -benchmem
+
+func BenchmarkDecryptAesCbc(b *testing.B) {
+	key, iv := []byte("YELLOW SUBMARINE"), make([]byte, 16)
+	cb := benchData(4 << 20)
+	b.SetBytes(int64(len(cb)))
+	for i := 0; i < b.N; i++ {
+		crytin.DecryptAesCbc(cb, key, iv)
+	}
+}
+
+func BenchmarkDecryptAesCbcParallel(b *testing.B) {
+	key, iv := []byte("YELLOW SUBMARINE"), make([]byte, 16)
+	cb := benchData(4 << 20)
+	b.SetBytes(int64(len(cb)))
+	for i := 0; i < b.N; i++ {
+		crytin.DecryptAesCbcParallel(cb, key, iv, 0)
+	}
+}
+
+// streamEncrypt : pb through an EncryptWriter in uneven writes
+func streamEncrypt(t *testing.T, pb []byte, mode crytin.Mode, key, iv []byte) []byte {
+	var cb bytes.Buffer
+	w, err := crytin.NewEncryptWriter(&cb, mode, key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(pb); i += 1000 {
+		end := i + 1000
+		if end > len(pb) {
+			end = len(pb)
+		}
+		w.Write(pb[i:end])
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return cb.Bytes()
+}
+
+func TestStreamModes(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := benchData(16)
+
+	// block aligned input ending in a byte <= 8 and > 8 used to be padded
+	// differently by the []byte modes
+	low, high := benchData(64), benchData(64)
+	low[63], high[63] = 0x04, 0x41
+	inputs := [][]byte{{}, []byte("YELLOW SUBMARINE"), low, high, benchData(1 << 20), benchData(1<<20 + 5)}
+
+	for _, pb := range inputs {
+		for _, m := range []struct {
+			mode crytin.Mode
+			iv   []byte
+		}{{crytin.ModeECB, nil}, {crytin.ModeCBC, iv}, {crytin.ModeCTR, iv[:8]}} {
+			cb := streamEncrypt(t, pb, m.mode, key, m.iv)
+
+			var want, back []byte
+			switch m.mode {
+			case crytin.ModeECB:
+				want, _ = crytin.EncryptAesEcb(pb[:len(pb):len(pb)], key)
+				back, _ = crytin.DecryptAesEcb(cb, key)
+			case crytin.ModeCBC:
+				want, _ = crytin.EncryptAesCbc(pb[:len(pb):len(pb)], key, iv)
+				back, _ = crytin.DecryptAesCbc(cb, key, iv)
+			case crytin.ModeCTR:
+				want, _ = crytin.EncryptAesCtr(pb, key, iv[:8])
+				back, _ = crytin.DecryptAesCtr(cb, key, iv[:8])
+			}
+			if !bytes.Equal(cb, want) {
+				t.Errorf("mode %d, %d bytes: stream encryption differs from []byte encryption", m.mode, len(pb))
+			}
+			if !bytes.Equal(back, pb) {
+				t.Errorf("mode %d, %d bytes: []byte decryption of stream output failed", m.mode, len(pb))
+			}
+
+			// the reader reads the []byte output
+			r, err := crytin.NewDecryptReader(iotest.HalfReader(bytes.NewReader(want)), m.mode, key, m.iv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(m.mode, len(pb), err)
+			}
+			if !bytes.Equal(got, pb) {
+				t.Errorf("mode %d, %d bytes: stream decryption of []byte output failed", m.mode, len(pb))
+			}
+		}
+	}
+}
+
+func TestStreamBadPadding(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := make([]byte, 16)
+
+	// a full block of plain text still gets a padding block
+	var cb bytes.Buffer
+	w, _ := crytin.NewEncryptWriter(&cb, crytin.ModeCBC, key, iv)
+	w.Write([]byte("YELLOW SUBMARINE"))
+	w.Close()
+	if cb.Len() != 32 {
+		t.Fatalf("expected a padding block, got %d bytes", cb.Len())
+	}
+
+	tampered := append([]byte{}, cb.Bytes()...)
+	tampered[len(tampered)-17] ^= 0x01 // last plain byte 0x10 => 0x11
+	r, _ := crytin.NewDecryptReader(bytes.NewReader(tampered), crytin.ModeCBC, key, iv)
+	if _, err := ioutil.ReadAll(r); err != crytin.ErrInvalidPadding {
+		t.Errorf("expected ErrInvalidPadding, got %v", err)
+	}
+
+	r, _ = crytin.NewDecryptReader(bytes.NewReader(cb.Bytes()[:20]), crytin.ModeCBC, key, iv)
+	if _, err := ioutil.ReadAll(r); err != crytin.ErrBlockSize {
+		t.Errorf("expected ErrBlockSize, got %v", err)
+	}
+}
@@ -4,9 +4,7 @@ import (
 	"github.com/srinivengala/cryptopals/crytin"
 
 	"bytes"
-	"math/rand"
 	"testing"
-	"time"
 )
 
 //Byte-at-a-time ECB decryption (Simple)
@@ -99,8 +97,12 @@ const ks = 16
 var unknownKey = [ks]byte{}
 
 func init() {
-	rand.Seed(time.Now().Unix())
-	rand.Read(unknownKey[:])
+	// seeded reader: every run replays the same key
+	key, err := crytin.RandomKey(crytin.NewSeededReader(12))
+	if err != nil {
+		panic(err)
+	}
+	copy(unknownKey[:], key)
 }
 
 func oracle(cb []byte, insertPoint int) ([]byte, error) {
@@ -2,14 +2,13 @@ package main
 
 import (
 	"github.com/srinivengala/cryptopals/crytin"
+	"github.com/srinivengala/cryptopals/crytin/cookie"
 
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
-	"strconv"
-	"strings"
 	"testing"
-	"time"
 )
 
 //ECB cut-and-paste
@@ -69,53 +68,17 @@ import (
 var _unknownKey []byte
 
 func init() {
-	const ks = 16
-	_unknownKey = make([]byte, ks)
-	rand.Seed(time.Now().Unix())
-	rand.Read(_unknownKey[:])
-}
-
-type user struct {
-	Email string
-	UID   int
-	Role  string
-}
-
-func newUser(email string) (*user, error) {
-	if hasMetaChars(email) {
-		return nil, errors.New("Illegal character in email id")
-	}
-	return &user{Email: email, UID: 10, Role: "user"}, nil
-}
-
-func (u *user) URLEncode() string {
-	return fmt.Sprintf("email=%s&uid=%d&role=%s", u.Email, u.UID, u.Role)
-}
-
-func (u *user) URLDecode(s string) {
-	for _, kv := range strings.Split(s, "&") {
-		ss := strings.Split(kv, "=")
-		switch ss[0] {
-		case "email":
-			u.Email = ss[1]
-		case "uid":
-			u.UID, _ = strconv.Atoi(ss[1])
-		case "role":
-			u.Role = ss[1]
-		}
+	var err error
+	if _unknownKey, err = crytin.RandomKey(crytin.NewSeededReader(13)); err != nil {
+		panic(err)
 	}
 }
 
-func hasMetaChars(s string) bool {
-	return strings.ContainsAny(s, "&=")
-}
-
 func oracleEmail(email string) ([]byte, error) {
-	u, err := newUser(email)
+	encoded, err := cookie.ProfileFor(email).Encode(cookie.Reject)
 	if err != nil {
 		return nil, err
 	}
-	encoded := u.URLEncode()
 	fmt.Println("Encrypting : ", crytin.ToSafeString([]byte(encoded)))
 	cb, _ := crytin.EncryptAesEcb([]byte(encoded), _unknownKey)
 	return cb, nil
@@ -126,18 +89,18 @@ func verifyAdmin(cb []byte) bool {
 	//fmt.Println(" The decrypted : ", crytin.ToHex(pb))
 	fmt.Println(" The decrypted : ", string(pb))
 
-	u := new(user)
-	u.URLDecode(string(pb))
-	if u.Role == "admin" {
-		return true
+	u, err := cookie.ParseProfile(string(pb))
+	if err != nil {
+		fmt.Println(" Rejected : ", err)
+		return false
 	}
-	return false
+	return u.Role == "admin"
 }
 
 func TestPrivilegeEsclation(t *testing.T) {
 	_ = t
 	const ks = 16
-	if _, err := newUser("te&st@test.com"); err == nil {
+	if _, err := oracleEmail("te&st@test.com"); err == nil {
 		t.Error("Could not detect metacharacters in email")
 	}
 
@@ -162,3 +125,166 @@ func TestPrivilegeEsclation(t *testing.T) {
 	}
 	t.Log("Privilege escalation successful")
 }
+
+// c13Oracle : profile_for as a template oracle for the cut-and-paste planner
+type c13Oracle struct{}
+
+func (o c13Oracle) Encrypt(input []byte) ([]byte, error) {
+	return oracleEmail(string(input))
+}
+
+func TestPrivilegeEsclationPlanned(t *testing.T) {
+	const ks = 16
+	tpl := crytin.CutPasteTemplate{
+		Prefix:    []byte("email="),
+		Suffix:    []byte("&uid=10&role=user"),
+		Forbidden: []byte("&="),
+		BlockSize: ks,
+	}
+	target := []byte("email=foo12@bar.com&uid=10&role=admin")
+
+	plan, err := crytin.PlanCutAndPaste(tpl, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, piece := range plan {
+		t.Logf("block %d <= cb[%d] of %q", i, piece.Block, piece.Input)
+	}
+
+	cb, err := crytin.AttackECBCutAndPaste(c13Oracle{}, tpl, target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyAdmin(cb) {
+		t.Error("Could not do privilege esclation with planned cut-and-paste")
+	}
+
+	// "=admin" can only come from the input, which refuses '='
+	if _, err := crytin.PlanCutAndPaste(tpl, []byte("email=foo@bar.com&role=admin&uid=10")); err == nil {
+		t.Error("Expected no alignment for a block needing a filtered '='")
+	}
+}
+
+func TestPlanCutAndPasteEdges(t *testing.T) {
+	const ks = 16
+	tpl := crytin.CutPasteTemplate{
+		Prefix:    []byte("email="),
+		Suffix:    []byte("&uid=10&role=user"),
+		Forbidden: []byte("&="),
+		BlockSize: ks,
+	}
+	if _, err := crytin.PlanCutAndPaste(tpl, nil); !errors.Is(err, crytin.ErrEmptyTarget) {
+		t.Errorf("expected ErrEmptyTarget, got %v", err)
+	}
+
+	// a whole block, ending in a byte <= 8 or not: padding is a full extra
+	// block, the profile_for oracle (EncryptAesEcb) has to pad it the same way
+	for _, target := range [][]byte{[]byte("email=foo@bar.c\x02"), []byte("email=foo@bar.co")} {
+		plan, err := crytin.PlanCutAndPaste(tpl, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan) != 2 {
+			t.Fatalf("%q: expected 2 blocks, got %d", target, len(plan))
+		}
+		cb, err := crytin.AttackECBCutAndPaste(c13Oracle{}, tpl, target, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pb, err := crytin.DecryptAesEcb(cb, _unknownKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pb, target) {
+			t.Errorf("forged %q, want %q", pb, target)
+		}
+	}
+}
+
+func TestCookieParse(t *testing.T) {
+	c, err := cookie.Parse("foo=bar&baz=qux&zap=zazzle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get("baz"); v != "qux" || c.Len() != 3 {
+		t.Errorf("unexpected parse %v", c.Pairs())
+	}
+
+	c, err = cookie.Parse(`email=foo%26role%3Dadmin@bar.com&note="a&b=\"c\""&empty=`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get("email"); v != "foo&role=admin@bar.com" {
+		t.Errorf("escape not decoded: %q", v)
+	}
+	if v, _ := c.Get("note"); v != `a&b="c"` {
+		t.Errorf("quote not decoded: %q", v)
+	}
+	if v, ok := c.Get("empty"); !ok || v != "" {
+		t.Errorf("empty value lost")
+	}
+
+	bad := map[string]error{
+		"role=user&role=admin": cookie.ErrDuplicateKey,
+		"email=a@b.com&admin":  cookie.ErrMissingValue,
+		"=admin":               cookie.ErrEmptyKey,
+		"email=a%2":            cookie.ErrBadEscape,
+		"email=a%zz":           cookie.ErrBadEscape,
+		`email="a@b.com`:       cookie.ErrBadQuote,
+		`email="a"b`:           cookie.ErrBadQuote,
+		"email=a=b":            cookie.ErrMetaChars,
+	}
+	for s, want := range bad {
+		if _, err := cookie.Parse(s); !errors.Is(err, want) {
+			t.Errorf("Parse(%q) = %v, want %v", s, err, want)
+		}
+	}
+}
+
+func TestCookieEncodePolicies(t *testing.T) {
+	p := cookie.ProfileFor("foo@bar.com&role=admin")
+
+	if _, err := p.Encode(cookie.Reject); !errors.Is(err, cookie.ErrMetaChars) {
+		t.Error("Reject policy encoded metacharacters")
+	}
+
+	for _, policy := range []cookie.Policy{cookie.Escape, cookie.Quote} {
+		s, err := p.Encode(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("policy %d : %s", policy, s)
+		u, err := cookie.ParseProfile(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Role != "user" || u.Email != p.Email {
+			t.Errorf("policy %d let the email inject a role: %+v", policy, u)
+		}
+	}
+}
+
+func TestCookieJSON(t *testing.T) {
+	c, err := cookie.Parse("email=foo@bar.com&uid=10&role=user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(js) != `{"email":"foo@bar.com","uid":"10","role":"user"}` {
+		t.Errorf("unexpected JSON %s", js)
+	}
+
+	c2 := &cookie.Cookie{}
+	if err := json.Unmarshal(js, c2); err != nil {
+		t.Fatal(err)
+	}
+	if c2.String() != c.String() {
+		t.Errorf("round trip %q != %q", c2.String(), c.String())
+	}
+	if err := json.Unmarshal([]byte(`{"role":"user","role":"admin"}`), c2); !errors.Is(err, cookie.ErrDuplicateKey) {
+		t.Errorf("duplicate JSON key accepted: %v", err)
+	}
+}
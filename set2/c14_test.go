@@ -3,18 +3,16 @@ package main
 import (
 	"github.com/srinivengala/cryptopals/crytin"
 
-	"math/rand"
 	"testing"
-	"time"
 )
 
 var c14UnknownKey []byte
 
 func init() {
-	const ks = 16
-	c14UnknownKey = make([]byte, ks)
-	rand.Seed(time.Now().Unix())
-	rand.Read(c14UnknownKey[:])
+	var err error
+	if c14UnknownKey, err = crytin.RandomKey(crytin.NewSeededReader(14)); err != nil {
+		panic(err)
+	}
 }
 
 type _oracle struct{}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/srinivengala/cryptopals/crytin"
+
+	"bytes"
+	"testing"
+)
+
+//CBC bitflipping attacks
+//
+// comment1=cooking%20MCs;userdata=<your-string>;comment2=%20like%20a%20pound%20of%20bacon
+//
+// The function should quote out the ";" and "=" characters.
+// Modify the ciphertext (without knowledge of the AES key) to accomplish ";admin=true;"
+
+// Notes:
+// "comment1=cooking%20MCs;userdata=" is 32 bytes, user data starts block 2
+// send 2 blocks of A's: block 2 gets scrambled, block 3 is flipped
+//   AAAAAAAAAAAAAAAA => XOR(AAAAA..., ;admin=true;AAAA) into block 2 of cb
+
+func TestCBCBitFlipping(t *testing.T) {
+	const ks = 16
+	oracle, err := crytin.NewCBCCommentOracle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := oracle.IV()
+
+	// quoting keeps the direct way out
+	cb, err := oracle.Encrypt([]byte(";admin=true;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin, _ := oracle.IsAdmin(cb, iv); admin {
+		t.Fatal("user data was not quoted")
+	}
+
+	known := bytes.Repeat([]byte("A"), ks)
+	want := []byte(";admin=true;AAAA")
+	cb, err = oracle.Encrypt(append(bytes.Repeat([]byte("A"), ks), known...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offset := crytin.CommentPrefixLen + ks
+	tcb, tiv, scrambled, err := crytin.FlipCBC(cb, iv, offset, known, want, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pb, err := oracle.Decrypt(tcb, tiv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("scrambled block %d : %s", scrambled, crytin.ToSafeString(pb))
+	if scrambled != offset/ks-1 {
+		t.Errorf("expected block %d scrambled, got %d", offset/ks-1, scrambled)
+	}
+	if admin, err := oracle.IsAdmin(tcb, tiv); err != nil || !admin {
+		t.Error("Could not flip in ;admin=true;", err)
+	}
+}
+
+func TestCBCBitFlippingFirstBlock(t *testing.T) {
+	const ks = 16
+	oracle, err := crytin.NewCBCCommentOracle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := oracle.IV()
+
+	cb, err := oracle.Encrypt([]byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first block only needs the iv, nothing is scrambled
+	tcb, tiv, scrambled, err := crytin.FlipCBC(cb, iv, 0, []byte("comment1=cooking"), []byte(";admin=true;abcd"), ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scrambled != -1 || !bytes.Equal(tcb, cb) {
+		t.Errorf("expected only the iv to change, scrambled %d", scrambled)
+	}
+	if admin, err := oracle.IsAdmin(tcb, tiv); err != nil || !admin {
+		t.Error("Could not flip in ;admin=true; through the iv", err)
+	}
+
+	if _, _, _, err := crytin.FlipCBC(cb, iv, ks-2, []byte("abcd"), []byte("wxyz"), ks); err != crytin.ErrFlipSpan {
+		t.Errorf("expected ErrFlipSpan, got %v", err)
+	}
+	if _, _, _, err := crytin.FlipCBC(cb, iv[:4], 0, []byte("c"), []byte(";"), ks); err != crytin.ErrBlockSize {
+		t.Errorf("expected ErrBlockSize for a short iv, got %v", err)
+	}
+	if _, _, _, err := crytin.FlipCBC(cb, iv, 0, []byte("c"), []byte(";"), 0); err != crytin.ErrBlockSize {
+		t.Errorf("expected ErrBlockSize for a zero block size, got %v", err)
+	}
+}
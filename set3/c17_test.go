@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+	"github.com/srinivengala/cryptopals/crytin/cookie"
 )
 
 // cb(i-1) XOR dec(key,cb(i)) = pb(i)
@@ -17,6 +21,188 @@ import (
 // (..R 03 03 XOR ..00 kpt2 kpt1) XOR dec(key, cb(i)) = tb ; R where tb[ks-3],tb[ks-2],tb[ks-1] = 0x03 a valid padding
 // then kpt3 = kpb(i)[ks-2] = R XOR 0x03
 
-func testCBCPaddingOracle(t testing.T) {
+// go test
+// go test -v
+
+const ks = 16
+
+var c17Strings = []string{
+	"MDAwMDAwTm93IHRoYXQgdGhlIHBhcnR5IGlzIGp1bXBpbmc=",
+	"MDAwMDAxV2l0aCB0aGUgYmFzcyBraWNrZWQgaW4gYW5kIHRoZSBWZWdhJ3MgYXJlIHB1bXBpbic=",
+	"MDAwMDAyUXVpY2sgdG8gdGhlIHBvaW50LCB0byB0aGUgcG9pbnQsIG5vIGZha2luZw==",
+	"MDAwMDAzQ29va2luZyBNQydzIGxpa2UgYSBwb3VuZCBvZiBiYWNvbg==",
+	"MDAwMDA0QnVybmluZyAnZW0sIGlmIHlvdSBhaW4ndCBxdWljayBhbmQgbmltYmxl",
+	"MDAwMDA1SSBnbyBjcmF6eSB3aGVuIEkgaGVhciBhIGN5bWJhbCB0aGUgc291bmQgb2YgYSBjeW1iYWw=",
+	"MDAwMDA2QW5kIGEgaGlnaCBoYXQgd2l0aCBhIHNvdXBlZCB1cCB0ZW1wbw==",
+	"MDAwMDA3SSdtIG9uIGEgcm9sbCwgaXQncyB0aW1lIHRvIGdvIHNvbG8=",
+	"MDAwMDA4b2xsaW4nIGluIG15IGZpdmUgcG9pbnQgb2g=",
+	"MDAwMDA5aXRoIG15IHJhZy10b3AgZG93biBzbyBteSBoYWlyIGNhbiBibG93",
+}
+
+// c17Seed : oracles read their keys from a seeded reader, reading the same
+// seed again replays the secret key for checking
+const c17Seed = 17
+
+var c17Key []byte
+
+func init() {
+	var err error
+	if c17Key, err = crytin.RandomKey(crytin.NewSeededReader(c17Seed)); err != nil {
+		panic(err)
+	}
+}
+
+func c17Oracle(t *testing.T) *crytin.CBCPaddingOracle {
+	oracle, err := crytin.NewCBCPaddingOracle(crytin.NewSeededReader(c17Seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oracle
+}
+
+func TestCBCPaddingOracle(t *testing.T) {
+	oracle := c17Oracle(t)
+	rnd := crytin.NewSeededReader(c17Seed + 1)
+
+	for _, s := range c17Strings {
+		pb, err := crytin.FromBase64String(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		iv, err := crytin.RandomIV(rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cb, err := oracle.Encrypt(pb, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !oracle.ValidPadding(cb, iv) {
+			t.Fatal("oracle rejects its own cipher text")
+		}
+
+		decrypted, err := crytin.AttackCBCPaddingOracle(oracle, cb, iv, ks, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("Decrypted : %s", decrypted)
+		if !bytes.Equal(decrypted, pb) {
+			t.Errorf("expected %q, got %q", pb, decrypted)
+		}
+	}
+}
+
+func TestCBCPaddingOracleFalsePositive(t *testing.T) {
+	oracle := c17Oracle(t)
+	iv := make([]byte, ks)
+
+	// dec(key, cb) = pb under a zero IV, so the first guess for the last byte
+	// that gives valid padding is the one making ..02 02, not ..?? 01
+	pb := []byte("YELLOW SUBMARI\x02\x02")
+	cb, err := crytin.EncryptAesCbcRaw(pb, c17Key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := crytin.AttackCBCPaddingOracle(oracle, cb, iv, ks, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, pb[:ks-2]) {
+		t.Errorf("expected %q, got %q", pb[:ks-2], decrypted)
+	}
+}
+
+// c17CookieOracle : AES-CBC encrypted profile cookies, parsing errors are not leaked
+// but padding errors are, like many real frameworks
+type c17CookieOracle struct {
+	key []byte
+}
+
+func (o c17CookieOracle) decrypt(cb, iv []byte) ([]byte, error) {
+	pb, err := crytin.DecryptAesCbcRaw(cb, o.key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return pb, crytin.PKCS7Unpad(&pb, ks)
+}
+
+func (o c17CookieOracle) ValidPadding(cb, iv []byte) bool {
+	_, err := o.decrypt(cb, iv)
+	return err == nil
+}
+
+func (o c17CookieOracle) IsAdmin(cb, iv []byte) bool {
+	pb, err := o.decrypt(cb, iv)
+	if err != nil {
+		return false
+	}
+	p, err := cookie.ParseProfile(string(pb))
+	return err == nil && p.Role == "admin"
+}
+
+func TestForgeCBCPaddingOracle(t *testing.T) {
+	// plain padding oracle
+	oracle := c17Oracle(t)
+	want := []byte("Forged without the key, one block at a time")
+	cb, iv, err := crytin.ForgeCBCPaddingOracle(oracle, want, ks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := crytin.DecryptAesCbcRaw(cb, c17Key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crytin.PKCS7Unpad(&pb, ks) != nil || !bytes.Equal(pb, want) {
+		t.Errorf("expected %q, got %q", want, pb)
+	}
+
+	// comment string oracle: ';' and '=' quoting does not matter any more
+	comments, err := crytin.NewCBCCommentOracle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, iv, err = crytin.ForgeCBCPaddingOracle(comments, []byte("comment1=x;admin=true;comment2=y"), ks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin, err := comments.IsAdmin(cb, iv); err != nil || !admin {
+		t.Error("Could not forge ;admin=true; comment", err)
+	}
+
+	// profile cookie oracle
+	cookies := c17CookieOracle{key: c17Key}
+	cb, iv, err = crytin.ForgeCBCPaddingOracle(cookies, []byte("email=foo@bar.com&uid=10&role=admin"), ks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cookies.IsAdmin(cb, iv) {
+		t.Error("Could not forge role=admin cookie")
+	}
+}
+
+func TestForgeCBCPaddingOracleReplay(t *testing.T) {
+	// same seeds, same oracle key and same random block: bit for bit the same forgery
+	forge := func() ([]byte, []byte) {
+		cb, iv, err := crytin.ForgeCBCPaddingOracle(c17Oracle(t), []byte("replayed"), ks, crytin.NewSeededReader(c17Seed+2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cb, iv
+	}
+	cb1, iv1 := forge()
+	cb2, iv2 := forge()
+	if !bytes.Equal(cb1, cb2) || !bytes.Equal(iv1, iv2) {
+		t.Error("seeded forgeries differ")
+	}
 
+	// crypto/rand: a fresh random block each time
+	cb3, _, err := crytin.ForgeCBCPaddingOracle(c17Oracle(t), []byte("replayed"), ks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(cb1, cb3) {
+		t.Error("unseeded forgery repeats the seeded one")
+	}
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Implement the MT19937 Mersenne Twister RNG
+//
+// You can get the pseudocode for this from Wikipedia.
+// If you're writing in Python, Ruby, or (gah) PHP, your language is probably already giving you MT19937 as "rand()"; don't use rand(). Write the RNG yourself.
+
+// Reference outputs:
+//   mt19937ar.out    : init_by_array({0x123, 0x234, 0x345, 0x456})
+//   mt19937-64.out   : init_by_array64({0x12345, 0x23456, 0x34567, 0x45678})
+//   C++ std::mt19937 and std::mt19937_64 : 10000th output for the default seed 5489
+
+// go test
+// go test -v
+
+func TestMT19937(t *testing.T) {
+	m := crytin.NewMT19937(5489)
+	var v uint32
+	for i := 0; i < 10000; i++ {
+		v = m.Uint32()
+	}
+	if v != 4123659995 {
+		t.Errorf("10000th output of seed 5489: got %d", v)
+	}
+
+	m.SeedByArray([]uint32{0x123, 0x234, 0x345, 0x456})
+	want := []uint32{1067595299, 955945823, 477289528, 4107218783, 4228976476,
+		3344332714, 3355579695, 227628506, 810200273, 2591290167}
+	for i, w := range want {
+		if v := m.Uint32(); v != w {
+			t.Errorf("init_by_array output %d: got %d want %d", i, v, w)
+		}
+	}
+}
+
+func TestMT19937x64(t *testing.T) {
+	m := crytin.NewMT19937x64(5489)
+	var v uint64
+	for i := 0; i < 10000; i++ {
+		v = m.Uint64()
+	}
+	if v != 9981545732273789042 {
+		t.Errorf("10000th output of seed 5489: got %d", v)
+	}
+
+	m.SeedByArray([]uint64{0x12345, 0x23456, 0x34567, 0x45678})
+	want := []uint64{7266447313870364031, 4946485549665804864, 16945909448695747420,
+		16394063075524226720, 4873882236456199058}
+	for i, w := range want {
+		if v := m.Uint64(); v != w {
+			t.Errorf("init_by_array64 output %d: got %d want %d", i, v, w)
+		}
+	}
+}
+
+func TestMT19937SeedByEmptyArray(t *testing.T) {
+	m1, m2 := crytin.NewMT19937(0), crytin.NewMT19937(0)
+	m1.SeedByArray(nil)
+	m2.SeedByArray([]uint32{0})
+	for i := 0; i < 700; i++ {
+		if m1.Uint32() != m2.Uint32() {
+			t.Fatalf("output %d differs from key {0}", i)
+		}
+	}
+
+	m3, m4 := crytin.NewMT19937x64(0), crytin.NewMT19937x64(0)
+	m3.SeedByArray([]uint64{})
+	m4.SeedByArray([]uint64{0})
+	for i := 0; i < 400; i++ {
+		if m3.Uint64() != m4.Uint64() {
+			t.Fatalf("64-bit output %d differs from key {0}", i)
+		}
+	}
+}
+
+func TestMT19937Reader(t *testing.T) {
+	m1, m2 := crytin.NewMT19937(42), crytin.NewMT19937(42)
+
+	// odd sized reads see the same little-endian stream
+	b := make([]byte, 40)
+	m1.Read(b[:3])
+	m1.Read(b[3:17])
+	m1.Read(b[17:])
+	for i := 0; i < len(b); i += 4 {
+		if v := binary.LittleEndian.Uint32(b[i:]); v != m2.Uint32() {
+			t.Fatalf("Read byte %d does not follow Uint32", i)
+		}
+	}
+
+	b64 := make([]byte, 16)
+	crytin.NewMT19937x64(42).Read(b64)
+	m64 := crytin.NewMT19937x64(42)
+	want := make([]byte, 16)
+	binary.LittleEndian.PutUint64(want, m64.Uint64())
+	binary.LittleEndian.PutUint64(want[8:], m64.Uint64())
+	if !bytes.Equal(b64, want) {
+		t.Error("MT19937x64 Read does not follow Uint64")
+	}
+}
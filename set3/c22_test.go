@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Crack an MT19937 seed
+//
+// Write a routine that performs the following operation:
+//     Wait a random number of seconds between, I don't know, 40 and 1000.
+//     Seeds the RNG with the current Unix timestamp
+//     Waits a random number of seconds again.
+//     Returns the first 32 bit output of the RNG.
+// From the 32 bit RNG output, discover the seed.
+
+// Notes: no real waiting, the clock is moved instead
+
+// go test
+// go test -v
+
+func TestRecoverMT19937Seed(t *testing.T) {
+	now := time.Now()
+	seeded := now.Add(-time.Duration(40+rand.Intn(960)) * time.Second)
+	output := crytin.NewMT19937(uint32(seeded.Unix())).Uint32()
+
+	seed, ok := crytin.RecoverMT19937Seed(output, now.Add(-2*time.Hour), now)
+	if !ok || seed != uint32(seeded.Unix()) {
+		t.Fatalf("seed not recovered, got %d ok %v", seed, ok)
+	}
+	t.Logf("Recovered seed : %d (%s)", seed, time.Unix(int64(seed), 0))
+}
+
+// The set2 tests used to do
+//
+//   rand.Seed(time.Now().Unix())
+//   rand.Read(unknownKey[:])
+//
+// they now read their keys from crytin.NewSeededReader with a fixed seed.
+// The old mistake, made with an explicit rand.New(rand.NewSource(...)) as
+// rand.Seed is a no-op since Go 1.24: any random bytes read later from the
+// same source, a prefix or an IV, give the seed away.
+// TestRecoverOracleKey makes it through the oracle constructors.
+func TestRecoverMathRandKey(t *testing.T) {
+	now := time.Now()
+	seeded := now.Add(-time.Duration(rand.Intn(3600)) * time.Second)
+
+	src := rand.New(rand.NewSource(seeded.Unix()))
+	unknownKey := make([]byte, ks)
+	src.Read(unknownKey)
+	visible := make([]byte, 8) // e.g. a random prefix the attacker sees
+	src.Read(visible)
+
+	seed, ok := crytin.RecoverMathRandSeed(visible, ks, now.Add(-24*time.Hour), now)
+	if !ok {
+		t.Fatal("seed not recovered")
+	}
+	key := crytin.MathRandBytes(seed, 0, ks)
+	if !bytes.Equal(key, unknownKey) {
+		t.Fatalf("recovered key %s, want %s", crytin.ToHex(key), crytin.ToHex(unknownKey))
+	}
+	t.Logf("Recovered seed %d and key %s", seed, crytin.ToHex(key))
+
+	// the key decrypts what the oracle encrypts
+	cb, _ := crytin.EncryptAesEcb([]byte("Rollin' in my 5.0"), unknownKey)
+	pb, _ := crytin.DecryptAesEcb(cb, key)
+	if string(pb) != "Rollin' in my 5.0" {
+		t.Errorf("recovered key does not decrypt: %q", pb)
+	}
+}
+
+// The oracles take their key, then their IV, from the reader they are given.
+// Handed a reader seeded with the time, the IV the attacker sees gives the
+// seed away, and with it the key read just before.
+func TestRecoverOracleKey(t *testing.T) {
+	now := time.Now()
+	seeded := now.Add(-time.Duration(rand.Intn(3600)) * time.Second)
+
+	o, err := crytin.NewCBCCommentOracle(crytin.NewSeededReader(uint32(seeded.Unix())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seed, ok := crytin.RecoverSeededReaderSeed(o.IV(), crytin.KeySize, now.Add(-24*time.Hour), now)
+	if !ok {
+		t.Fatal("seed not recovered")
+	}
+	key, err := crytin.RandomKey(crytin.NewSeededReader(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Recovered seed %d and key %s", seed, crytin.ToHex(key))
+
+	// the key decrypts what the oracle encrypts
+	cb, err := o.Encrypt([]byte("Rollin' in my 5.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := crytin.DecryptAesCbcRaw(cb, key, o.IV())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pb, []byte("Rollin' in my 5.0")) {
+		t.Errorf("recovered key does not decrypt: %q", pb)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Clone an MT19937 RNG from its output
+//
+// The internal state of MT19937 consists of 624 32 bit integers.
+// For each batch of 624 outputs, MT permutes that internal state.
+// By permuting state regularly, MT19937 achieves a period of 2**19937, which is Big.
+//
+// Each time MT19937 is tapped, an element of its internal state is subjected to a tempering function
+// that diffuses bits through the result.
+// The tempering function is invertible; you can write an "untemper" function that takes an MT19937 output
+// and transforms it back into the corresponding element of the MT19937 state array.
+//
+// Once you have "untemper" working, create a new MT19937 generator, tap it for 624 outputs,
+// untemper each of them to recreate the state of the generator, and splice that state into a new instance
+// of the MT19937 generator.
+// The new "spliced" generator should predict the values of the original.
+
+// go test
+// go test -v
+
+func TestUntemper(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		y := rand.Uint32()
+		if v := crytin.Untemper(crytin.Temper(y)); v != y {
+			t.Fatalf("Untemper(Temper(%08x)) = %08x", y, v)
+		}
+	}
+}
+
+func TestCloneMT19937(t *testing.T) {
+	m := crytin.NewMT19937(rand.Uint32())
+	// start mid-way through a twist cycle
+	for i := 0; i < 100; i++ {
+		m.Uint32()
+	}
+
+	outputs := make([]uint32, 700)
+	for i := range outputs {
+		outputs[i] = m.Uint32()
+	}
+	clone, err := crytin.CloneMT19937(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2000; i++ {
+		if clone.Uint32() != m.Uint32() {
+			t.Fatalf("clone diverged at output %d", i)
+		}
+	}
+
+	outputs[650] ^= 1
+	if _, err := crytin.CloneMT19937(outputs); err != crytin.ErrNotMT19937 {
+		t.Errorf("expected ErrNotMT19937, got %v", err)
+	}
+}
+
+func testPartialClone(t *testing.T, n int, mask func(i int) uint32) {
+	m := crytin.NewMT19937(rand.Uint32())
+	obs := make([]crytin.MTObservation, n)
+	for i := range obs {
+		obs[i].Mask = mask(i)
+		obs[i].Value = m.Uint32() & obs[i].Mask
+	}
+
+	clone, err := crytin.CloneMT19937Partial(obs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2000; i++ {
+		if clone.Uint32() != m.Uint32() {
+			t.Fatalf("clone diverged at output %d", i)
+		}
+	}
+}
+
+func TestCloneMT19937Missing(t *testing.T) {
+	// 30 outputs of the first window never seen
+	missing := map[int]bool{}
+	for len(missing) < 30 {
+		missing[1+rand.Intn(622)] = true
+	}
+	testPartialClone(t, 1300, func(i int) uint32 {
+		if missing[i] {
+			return 0
+		}
+		return 0xffffffff
+	})
+}
+
+func TestCloneMT19937Truncated(t *testing.T) {
+	// only the top 16 bits of every output, like rand() >> 16
+	testPartialClone(t, 2000, func(i int) uint32 { return 0xffff0000 })
+}
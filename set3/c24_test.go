@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Create the MT19937 stream cipher and break it
+//
+// You can create a trivial stream cipher out of any PRNG; use it to generate a sequence of 8 bit outputs and call those outputs a keystream.
+// XOR each byte of plaintext with each successive byte of keystream.
+// Write the function that does this for MT19937 using a 16-bit seed.
+// Verify that you can encrypt and decrypt properly.
+//
+// Use your function to encrypt a known plaintext (say, 14 consecutive 'A' characters) prefixed by a random number of random characters.
+// From the ciphertext, recover the "key" (the 16 bit seed).
+//
+// Use the same idea to generate a random "password reset token" using MT19937 seeded from the current time.
+// Write a function to check if any given password token is actually the product of an MT19937 PRNG seeded with the current time.
+
+// go test
+// go test -v
+
+func TestMT19937Cipher(t *testing.T) {
+	seed := uint16(mrand.Intn(0x10000))
+	prefix := make([]byte, 5+mrand.Intn(20))
+	rand.Read(prefix)
+	known := bytes.Repeat([]byte("A"), 14)
+	pb := append(prefix, known...)
+
+	cb := crytin.MT19937Cipher(pb, seed)
+	if !bytes.Equal(crytin.MT19937Cipher(cb, seed), pb) {
+		t.Fatal("MT19937Cipher does not decrypt")
+	}
+
+	recovered, ok := crytin.AttackMT19937Cipher(cb, known)
+	if !ok || recovered != seed {
+		t.Fatalf("seed not recovered, got %d want %d", recovered, seed)
+	}
+	t.Logf("Recovered seed : %d", recovered)
+
+	if _, ok := crytin.AttackMT19937Cipher(cb, nil); ok {
+		t.Error("empty known plain text reported a seed")
+	}
+	if _, ok := crytin.AttackMT19937Cipher(cb[:4], known); ok {
+		t.Error("known plain text longer than the cipher text reported a seed")
+	}
+}
+
+func TestDetectMT19937TimeToken(t *testing.T) {
+	now := time.Now()
+	issued := now.Add(-time.Duration(mrand.Intn(600)) * time.Second)
+	token := crytin.MT19937Token(uint32(issued.Unix()), 16)
+
+	seed, ok := crytin.DetectMT19937TimeToken(token, now, 15*time.Minute)
+	if !ok || int64(seed) != issued.Unix() {
+		t.Errorf("time seeded token not detected")
+	}
+
+	random := make([]byte, 16)
+	rand.Read(random)
+	if _, ok := crytin.DetectMT19937TimeToken(random, now, 15*time.Minute); ok {
+		t.Errorf("crypto/rand token flagged as MT19937")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Break "random access read/write" AES CTR
+//
+// Back to CTR. Encrypt the recovered plaintext from this file (the ECB exercise) under CTR with a random key.
+// Now, write the code that allows you to "seek" into the ciphertext, decrypt, and re-encrypt with different plaintext.
+// Expose this as a function, like, "edit(ciphertext, key, offset, newtext)".
+// Imagine the "edit" function was exposed to attackers by means of an API call that didn't reveal the key or the original plaintext;
+// the attacker has the ciphertext and controls the offset and "new text".
+// Recover the original plaintext.
+
+// go test
+// go test -v
+
+func TestAesCtr(t *testing.T) {
+	// challenge 18 vector, nonce 0
+	cb, _ := crytin.FromBase64String("L77na/nrFsKvynd6HzOoG7GHTLXsTVu9qvY/2syLXzhPweyyMTJULu/6/kXX0KSvoOLSFQ==")
+	pb, err := crytin.DecryptAesCtr(cb, []byte("YELLOW SUBMARINE"), make([]byte, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Plain text : %s", pb)
+	if string(pb) != "Yo, VIP Let's kick it Ice, Ice, baby Ice, Ice, baby " {
+		t.Errorf("unexpected CTR decryption %q", pb)
+	}
+}
+
+func TestEditAesCtr(t *testing.T) {
+	key, nonce := []byte("YELLOW SUBMARINE"), []byte("noncenon")
+	pb := []byte("The quick brown fox jumps over the lazy dog, twice over")
+	cb, _ := crytin.EncryptAesCtr(pb, key, nonce)
+
+	// overwrite across a block boundary, then past the end
+	for _, edit := range []struct {
+		offset  int
+		newtext string
+	}{{13, "cat sleeps"}, {50, "again and again"}} {
+		want := append([]byte{}, pb...)
+		want = append(want[:edit.offset], edit.newtext...)
+		if rest := edit.offset + len(edit.newtext); rest < len(pb) {
+			want = append(want, pb[rest:]...)
+		}
+
+		edited, err := crytin.EditAesCtr(cb, key, nonce, edit.offset, []byte(edit.newtext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := crytin.DecryptAesCtr(edited, key, nonce)
+		if !bytes.Equal(got, want) {
+			t.Errorf("edit at %d: got %q want %q", edit.offset, got, want)
+		}
+	}
+}
+
+func TestCtrNegativeOffset(t *testing.T) {
+	s, err := crytin.NewAesCtr([]byte("YELLOW SUBMARINE"), []byte("noncenon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if err := s.XORKeyStreamAt(buf, buf, -1); err != crytin.ErrOffset {
+		t.Errorf("XORKeyStreamAt at -1: got %v want ErrOffset", err)
+	}
+	if _, err := crytin.EditAesCtr(buf, []byte("YELLOW SUBMARINE"), []byte("noncenon"), -1, buf); err != crytin.ErrOffset {
+		t.Errorf("EditAesCtr at -1: got %v want ErrOffset", err)
+	}
+}
+
+func TestAttackCTREdit(t *testing.T) {
+	dat, err := ioutil.ReadFile("../data/7.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecb, err := crytin.FromBase64(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := crytin.DecryptAesEcb(ecb, []byte("YELLOW SUBMARINE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracle, err := crytin.NewCTREditOracle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := oracle.Encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := crytin.AttackCTREdit(oracle, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pb, secret) {
+		t.Error("Could not recover the plain text through the edit oracle")
+	}
+	t.Logf("Recovered %d bytes : %s...", len(pb), pb[:33])
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//CTR bitflipping
+//
+// There are people in the world that believe that CTR resists bit flipping attacks of the kind to which CBC mode is susceptible.
+// Re-implement the CBC bitflipping exercise from earlier to use CTR mode instead of CBC mode.
+// Inject an "admin=true" token.
+
+// go test
+// go test -v
+
+func TestCTRBitFlipping(t *testing.T) {
+	oracle, err := crytin.NewCTRCommentOracle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := oracle.Encrypt([]byte(";admin=true;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin, _ := oracle.IsAdmin(cb); admin {
+		t.Fatal("user data was not quoted")
+	}
+
+	// no block to sacrifice: flip the user data in place
+	known := []byte("XadminXtrueX")
+	want := []byte(";admin=true;")
+	cb, err = oracle.Encrypt(known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcb, err := crytin.FlipXOR(cb, crytin.CommentPrefixLen, known, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pb, _ := oracle.Decrypt(tcb)
+	t.Logf("Flipped : %s", pb)
+	expected := crytin.CommentString(known)
+	copy(expected[crytin.CommentPrefixLen:], want)
+	if !bytes.Equal(pb, expected) {
+		t.Errorf("bytes outside the flip changed: %q", pb)
+	}
+	if admin, err := oracle.IsAdmin(tcb); err != nil || !admin {
+		t.Error("Could not flip in ;admin=true;", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Recover the key from CBC with IV=Key
+//
+// Take your code from the CBC exercise and modify it so that it repurposes the key for CBC encryption as the IV.
+// Verify each byte of the plaintext for ASCII compliance (ie, look for high-ASCII values).
+// Noncompliant messages should raise an exception or return an error that includes the decrypted plaintext.
+//
+// AES-CBC(P_1, P_2, P_3) -> C_1, C_2, C_3
+// C_1, C_2, C_3 -> C_1, 0, C_1
+// P'_1 XOR P'_3 = key
+
+// go test
+// go test -v
+
+const ks = 16
+
+func TestCBCKeyAsIV(t *testing.T) {
+	// replaying the seeded reader gives the oracle's key for checking
+	oracle, err := crytin.NewCBCKeyAsIVOracle(crytin.NewSeededReader(27))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crytin.RandomKey(crytin.NewSeededReader(27))
+
+	cb, err := oracle.Encrypt([]byte("attack at dawn, or whenever"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := oracle.Decrypt(cb); err != nil {
+		t.Fatal("oracle rejects its own cipher text: ", err)
+	}
+
+	recovered, err := crytin.AttackCBCKeyAsIV(oracle, cb, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Recovered key : %s", crytin.ToHex(recovered))
+	if !bytes.Equal(recovered, key) {
+		t.Errorf("expected key %s", crytin.ToHex(key))
+	}
+}
+
+func TestCheckCBCIV(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	if _, err := crytin.EncryptAesCbc([]byte("hello"), key, key); err != crytin.ErrIVIsKey {
+		t.Errorf("EncryptAesCbc accepted IV=key: %v", err)
+	}
+	if _, err := crytin.DecryptAesCbc(make([]byte, ks), key, key); err != crytin.ErrIVIsKey {
+		t.Errorf("DecryptAesCbc accepted IV=key: %v", err)
+	}
+	if _, err := crytin.EncryptAesCbcRaw(make([]byte, ks), key, append([]byte{}, key...)); err != crytin.ErrIVIsKey {
+		t.Errorf("EncryptAesCbcRaw accepted IV=key: %v", err)
+	}
+	if err := crytin.CheckCBCIV(key, make([]byte, ks)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Implement a SHA-1 keyed MAC
+//
+// Find a SHA-1 implementation in the language you code in.
+// Write a function to authenticate a message under a secret key by using a secret-prefix MAC, which is simply:
+//   SHA1(key || message)
+// Verify that you cannot tamper with the message without breaking the MAC you've produced,
+// and that you can't produce a new MAC without knowing the secret key.
+
+// go test
+// go test -v
+
+func TestSHA1(t *testing.T) {
+	inputs := []string{
+		"",
+		"abc",
+		"abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq",
+		strings.Repeat("a", 55),
+		strings.Repeat("a", 56),
+		strings.Repeat("a", 64),
+		strings.Repeat("a", 1000),
+	}
+	for _, in := range inputs {
+		want := sha1.Sum([]byte(in))
+		if got := crytin.SHA1Sum([]byte(in)); !bytes.Equal(got, want[:]) {
+			t.Errorf("SHA1(%.10q...) = %x want %x", in, got, want)
+		}
+
+		// odd sized writes go through the buffer
+		d := crytin.NewSHA1()
+		for i := 0; i < len(in); i += 7 {
+			j := i + 7
+			if j > len(in) {
+				j = len(in)
+			}
+			d.Write([]byte(in[i:j]))
+		}
+		if got := d.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("chunked SHA1(%.10q...) = %x want %x", in, got, want)
+		}
+	}
+}
+
+func TestSHA1SetState(t *testing.T) {
+	// hashing a whole padded message, then carrying on from its digest
+	// is the same as hashing it all in one go
+	msg := []byte("message of some length")
+	padded := append(append([]byte{}, msg...), crytin.MDPadding(uint64(len(msg)), crytin.SHA1BlockSize, binary.BigEndian)...)
+	extra := []byte("and then some")
+
+	h, err := crytin.SHA1State(crytin.SHA1Sum(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := crytin.NewSHA1()
+	if err := d.SetState(h, uint64(len(padded))); err != nil {
+		t.Fatal(err)
+	}
+	d.Write(extra)
+
+	want := sha1.Sum(append(padded, extra...))
+	if got := d.Sum(nil); !bytes.Equal(got, want[:]) {
+		t.Errorf("got %x want %x", got, want)
+	}
+
+	if err := d.SetState(h, 10); err != crytin.ErrHashState {
+		t.Errorf("expected ErrHashState, got %v", err)
+	}
+}
+
+func TestMDPadding(t *testing.T) {
+	for n := 0; n < 200; n++ {
+		pad := crytin.MDPadding(uint64(n), 64, binary.BigEndian)
+		if (n+len(pad))%64 != 0 || len(pad) < 9 || len(pad) > 72 || pad[0] != 0x80 {
+			t.Fatalf("bad padding for %d bytes: %x", n, pad)
+		}
+		if binary.BigEndian.Uint64(pad[len(pad)-8:]) != uint64(n)*8 {
+			t.Fatalf("bad length field for %d bytes: %x", n, pad)
+		}
+	}
+	if pad := crytin.MDPadding(3, 64, binary.LittleEndian); pad[len(pad)-8] != 24 {
+		t.Errorf("expected little endian bit length, got %x", pad)
+	}
+}
+
+func TestSHA1MAC(t *testing.T) {
+	oracle, err := crytin.NewSHA1MACOracle(crytin.NewSeededReader(28), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("comment1=cooking%20MCs;userdata=foo;comment2=%20like%20a%20pound%20of%20bacon")
+	mac := oracle.Sign(msg)
+	if !oracle.Verify(msg, mac) {
+		t.Fatal("oracle rejects its own MAC")
+	}
+
+	tampered := append([]byte{}, msg...)
+	tampered[len(tampered)-1] ^= 1
+	if oracle.Verify(tampered, mac) {
+		t.Error("tampered message verified")
+	}
+	// a MAC without the key
+	if oracle.Verify(msg, crytin.SHA1Sum(msg)) {
+		t.Error("keyless MAC verified")
+	}
+}
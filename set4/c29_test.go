@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"strings"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Break a SHA-1 keyed MAC using length extension
+//
+// Secret-prefix SHA-1 MACs are trivially breakable.
+// Forge a variant of this message that ends with ";admin=true".
+//   "comment1=cooking%20MCs;userdata=foo;comment2=%20like%20a%20pound%20of%20bacon"
+// You don't know the key length, guess it.
+
+// go test
+// go test -v
+
+func TestMDFuncs(t *testing.T) {
+	funcs := []struct {
+		f   *crytin.MDFunc
+		std func() hash.Hash
+	}{
+		{crytin.SHA1Func, sha1.New},
+		{crytin.SHA256Func, sha256.New},
+		{crytin.MD5Func, md5.New},
+	}
+	for _, fn := range funcs {
+		for _, n := range []int{0, 3, 55, 56, 63, 64, 65, 1000} {
+			in := []byte(strings.Repeat("abc", n)[:n])
+			got := fn.f.Sum(in)
+
+			std := fn.std()
+			std.Write(in)
+			want := std.Sum(nil)
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s of %d bytes = %x want %x", fn.f.Name, n, got, want)
+			}
+
+			h := fn.f.New()
+			for i := 0; i < n; i += 9 {
+				j := i + 9
+				if j > n {
+					j = n
+				}
+				h.Write(in[i:j])
+			}
+			if got := h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("chunked %s of %d bytes = %x want %x", fn.f.Name, n, got, want)
+			}
+		}
+	}
+
+	// no MD4 in the standard library, RFC 1320 vectors instead
+	for in, want := range md4Vectors {
+		if got := crytin.ToHex(crytin.MD4Func.Sum([]byte(in))); got != want {
+			t.Errorf("%s(%q) = %s want %s", crytin.MD4Func.Name, in, got, want)
+		}
+	}
+}
+
+// lengthExtensionAttack : AttackSHA1LengthExtension and friends
+type lengthExtensionAttack func(oracle crytin.MACOracle, message, mac, extra []byte, maxKeyLen int) ([]byte, []byte, int, error)
+
+func TestLengthExtension(t *testing.T) {
+	const maxKeyLen = 64
+	msg := []byte("comment1=cooking%20MCs;userdata=foo;comment2=%20like%20a%20pound%20of%20bacon")
+	extra := []byte(";admin=true")
+	rnd := crytin.NewSeededReader(29)
+
+	toy16, err := crytin.NewToyFunc(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toy24, err := crytin.NewToyFunc(24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crytin.NewToyFunc(12); err == nil {
+		t.Error("toy hash accepted a 12 bit state")
+	}
+
+	for _, tc := range []struct {
+		f      *crytin.MDFunc
+		attack lengthExtensionAttack // nil for AttackMDLengthExtension
+		// a 16 or 24 bit MAC may verify for a wrong key length, still a forgery
+		anyKeyLen bool
+	}{
+		{crytin.SHA1Func, crytin.AttackSHA1LengthExtension, false}, // challenge 29
+		{crytin.MD4Func, crytin.AttackMD4LengthExtension, false},   // challenge 30
+		{crytin.SHA256Func, nil, false},
+		{crytin.MD5Func, nil, false},
+		{toy16, nil, true},
+		{toy24, nil, true},
+	} {
+		f, attack := tc.f, tc.attack
+		if attack == nil {
+			attack = func(oracle crytin.MACOracle, message, mac, extra []byte, maxKeyLen int) ([]byte, []byte, int, error) {
+				return crytin.AttackMDLengthExtension(f, oracle, message, mac, extra, maxKeyLen)
+			}
+		}
+
+		for n := 0; n <= maxKeyLen; n++ {
+			oracle, err := crytin.NewMDMACOracle(f, rnd, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mac := oracle.Sign(msg)
+			if len(mac) != len(f.IV) {
+				t.Fatalf("%s: %d byte MAC", f.Name, len(mac))
+			}
+			if oracle.IsAdmin(msg, mac) {
+				t.Fatalf("%s: admin before the attack", f.Name)
+			}
+
+			forged, forgedMAC, keyLen, err := attack(oracle, msg, mac, extra, maxKeyLen)
+			if err != nil {
+				t.Fatalf("%s, key length %d: %v", f.Name, n, err)
+			}
+			if keyLen != n && !tc.anyKeyLen {
+				t.Errorf("%s: guessed key length %d, was %d", f.Name, keyLen, n)
+			}
+			if !bytes.HasPrefix(forged, msg) || !bytes.HasSuffix(forged, extra) {
+				t.Errorf("%s: forged message %q", f.Name, forged)
+			}
+			if !oracle.IsAdmin(forged, forgedMAC) {
+				t.Errorf("%s, key length %d: forged message is not admin", f.Name, n)
+			}
+		}
+	}
+}
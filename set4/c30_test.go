@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Break an MD4 keyed MAC using length extension
+//
+// Second verse, same as the first, but use MD4 instead of SHA-1.
+// Having done this attack once against SHA-1, the MD4 variant should take much less time;
+// mostly just the time you'll spend Googling for an implementation of MD4.
+
+// The attack is TestLengthExtension in c29_test.go, run on every MDFunc.
+
+// go test
+// go test -v
+
+// md4Vectors : RFC 1320 appendix A.5
+var md4Vectors = map[string]string{
+	"":                           "31d6cfe0d16ae931b73c59d7e0c089c0",
+	"a":                          "bde52cb31de33e46245e05fbdbd6fb24",
+	"abc":                        "a448017aaf21d8525fc10ae87aa6729d",
+	"message digest":             "d9130a8164549fe818874806e1c7014b",
+	"abcdefghijklmnopqrstuvwxyz": "d79e1c308aa5bbcdeea8ed63df412da9",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789":                   "043f8582f241db351ce627e153e7f0e4",
+	"12345678901234567890123456789012345678901234567890123456789012345678901234567890": "e33b4ddc9c38f2199c3e7b164fcc0536",
+}
+
+func TestMD4(t *testing.T) {
+	for in, want := range md4Vectors {
+		if got := crytin.ToHex(crytin.MD4Sum([]byte(in))); got != want {
+			t.Errorf("MD4(%q) = %s want %s", in, got, want)
+		}
+
+		d := crytin.NewMD4()
+		for i := 0; i < len(in); i += 5 {
+			j := i + 5
+			if j > len(in) {
+				j = len(in)
+			}
+			d.Write([]byte(in[i:j]))
+		}
+		if got := crytin.ToHex(d.Sum(nil)); got != want {
+			t.Errorf("chunked MD4(%q) = %s want %s", in, got, want)
+		}
+	}
+}
+
+func TestMD4SetState(t *testing.T) {
+	msg := []byte("message of some length")
+	padded := append(append([]byte{}, msg...), crytin.MDPadding(uint64(len(msg)), crytin.MD4BlockSize, binary.LittleEndian)...)
+	extra := []byte("and then some")
+
+	h, err := crytin.MD4State(crytin.MD4Sum(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := crytin.NewMD4()
+	if err := d.SetState(h, uint64(len(padded))); err != nil {
+		t.Fatal(err)
+	}
+	d.Write(extra)
+
+	if got, want := d.Sum(nil), crytin.MD4Sum(append(padded, extra...)); !bytes.Equal(got, want) {
+		t.Errorf("got %x want %x", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Implement and break HMAC-SHA1 with an artificial timing leak
+//
+// http://localhost:9000/test?file=foo&signature=46b4ec586117154dacd49d664e5d63fdc88efb51
+// The server should have an HMAC key, and then verify that the "signature" on incoming requests is valid for "file",
+// using the "==" operator to compare the valid MAC for a file with the "signature" parameter
+// (in other words, verify the HMAC the way any normal programmer would verify it).
+// Write a function, call it "insecure_compare", that implements the == operation by doing byte-at-a-time comparisons with early exit
+// (ie, return false at the first non-matching byte).
+// In the loop for "insecure_compare", add a 50ms sleep (sleep 50ms after each byte).
+//
+//Break HMAC-SHA1 with a slightly less artificial timing leak (challenge 32)
+//
+// Reduce the sleep in your "insecure_compare" until your previous solution breaks. (Try 5ms to start.)
+// Now break it again.
+
+// go test
+// go test -v
+// CRYPTOPALS_TIMING=1 go test -v -timeout 30m -run TimingLeakAttack  (real timing, minutes)
+
+func TestHMACSHA1(t *testing.T) {
+	for _, key := range [][]byte{nil, []byte("key"), bytes.Repeat([]byte("k"), 64), bytes.Repeat([]byte("k"), 100)} {
+		msg := []byte("The quick brown fox jumps over the lazy dog")
+		std := hmac.New(sha1.New, key)
+		std.Write(msg)
+		if got, want := crytin.HMACSHA1(key, msg), std.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("%d byte key: got %x want %x", len(key), got, want)
+		}
+	}
+}
+
+func TestTimingLeakServer(t *testing.T) {
+	server, err := crytin.NewTimingLeakServer(crytin.NewSeededReader(31), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	sig := server.Signature([]byte("foo"))
+	for _, tc := range []struct {
+		sig  []byte
+		code int
+	}{{sig, http.StatusOK}, {append(sig[:19:19], sig[19]^1), http.StatusInternalServerError}, {sig[:10], http.StatusInternalServerError}} {
+		resp, err := http.Get(ts.URL + "/test?" + url.Values{"file": {"foo"}, "signature": {crytin.ToHex(tc.sig)}}.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.code {
+			t.Errorf("signature %x: status %d want %d", tc.sig, resp.StatusCode, tc.code)
+		}
+	}
+}
+
+func TestTimingLeakAttack(t *testing.T) {
+	if testing.Short() || os.Getenv("CRYPTOPALS_TIMING") == "" {
+		t.Skip("wall clock timing attack, set CRYPTOPALS_TIMING=1 to run")
+	}
+	server, err := crytin.NewTimingLeakServer(nil, 2*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	oracle := &crytin.HTTPTimingOracle{URL: ts.URL + "/test", File: "foo"}
+	start := time.Now()
+	sig, err := crytin.AttackTimingLeak(oracle, sha1.Size, crytin.TimingOptions{Samples: 2, Stat: crytin.TrimmedMean})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("signature %x in %v", sig, time.Since(start))
+	if want := server.Signature([]byte("foo")); !bytes.Equal(sig, want) {
+		t.Errorf("got %x want %x", sig, want)
+	}
+}
+
+// fakeTimingOracle : early exit compare against sig costing step per
+// matching byte plus seeded noise, no clock involved
+type fakeTimingOracle struct {
+	sig   []byte
+	step  time.Duration
+	noise *crytin.MT19937
+	calls int
+
+	// lie : probes with byte lieAt set to lieByte look 2 steps slower,
+	// for the next lies of them
+	lieAt   int
+	lieByte byte
+	lies    int
+}
+
+func (o *fakeTimingOracle) Time(signature []byte) (time.Duration, bool, error) {
+	o.calls++
+	var d time.Duration
+	for i := range o.sig {
+		if signature[i] != o.sig[i] {
+			break
+		}
+		d += o.step
+	}
+	d += time.Duration(o.noise.Uint32() % uint32(o.step/4))
+	if o.noise.Uint32()%64 == 0 {
+		d += 10 * o.step // scheduler hiccup
+	}
+	if o.lies > 0 && signature[o.lieAt] == o.lieByte {
+		o.lies--
+		d += 2 * o.step
+	}
+	return d, bytes.Equal(signature, o.sig), nil
+}
+
+func TestTimingLeakAttackFake(t *testing.T) {
+	server, err := crytin.NewTimingLeakServer(crytin.NewSeededReader(31), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := server.Signature([]byte("foo"))
+	oracle := &fakeTimingOracle{sig: want, step: time.Millisecond, noise: crytin.NewMT19937(31)}
+
+	sig, err := crytin.AttackTimingLeak(oracle, len(want), crytin.TimingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Errorf("got %x want %x", sig, want)
+	}
+	t.Logf("%d byte signature in %d checks", len(sig), oracle.calls)
+}
+
+func TestTimingLeakAttackStepBack(t *testing.T) {
+	want := crytin.HMACSHA1([]byte("key"), []byte("foo"))
+	// byte 3 is timed 3 samples over 8 rounds, the wrong value wins them
+	// all, byte 4 then has nothing standing out
+	oracle := &fakeTimingOracle{sig: want, step: time.Millisecond, noise: crytin.NewMT19937(32),
+		lieAt: 3, lieByte: want[3] ^ 0x5a, lies: 3 * 8}
+
+	sig, err := crytin.AttackTimingLeak(oracle, len(want), crytin.TimingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Errorf("got %x want %x", sig, want)
+	}
+	if oracle.lies != 0 {
+		t.Errorf("%d lies left, the wrong byte was not picked", oracle.lies)
+	}
+
+	// a liar that never stops runs out of retries
+	oracle = &fakeTimingOracle{sig: want, step: time.Millisecond, noise: crytin.NewMT19937(33),
+		lieAt: 3, lieByte: want[3] ^ 0x5a, lies: 1 << 30}
+	if _, err := crytin.AttackTimingLeak(oracle, len(want), crytin.TimingOptions{Retries: 2}); err != crytin.ErrTimingAttack {
+		t.Errorf("expected ErrTimingAttack, got %v", err)
+	}
+}
+
+func TestTimingStats(t *testing.T) {
+	ms := func(v ...int) []time.Duration {
+		ds := make([]time.Duration, len(v))
+		for i := range v {
+			ds[i] = time.Duration(v[i]) * time.Millisecond
+		}
+		return ds
+	}
+	// one GC pause does not move either
+	ds := ms(2, 1, 2, 90, 2, 2, 1, 3)
+	if m := crytin.Median(ds); m != 2*time.Millisecond {
+		t.Errorf("median %v", m)
+	}
+	if m := crytin.TrimmedMean(ds); m != 2*time.Millisecond {
+		t.Errorf("trimmed mean %v", m)
+	}
+	if m := crytin.Median(ms(5, 1, 3)); m != 3*time.Millisecond {
+		t.Errorf("odd median %v", m)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+)
+
+//Break HMAC-SHA1 with a slightly less artificial timing leak
+//
+// Reduce the sleep in your "insecure_compare" until your previous solution breaks. (Try 5ms to start.)
+// Now break it again.
+
+// Notes: the 2ms attack is TestTimingLeakAttack in c31_test.go. Here the
+// same question for crytin's own helpers, which of them leak? dudect: fixed
+// input against random inputs, Welch's t-test on the timings.
+// Timings depend on the machine and its load, the verdicts are only logged
+// unless CRYPTOPALS_TIMING is set.
+
+// go test
+// go test -v
+// CRYPTOPALS_TIMING=1 go test -v -run TimingLeak
+
+var timingVerdicts = os.Getenv("CRYPTOPALS_TIMING") != ""
+
+// leakTest : runs dudect on test, a verdict other than leaky fails the test
+// under CRYPTOPALS_TIMING and is logged otherwise
+func leakTest(t *testing.T, name string, leaky bool, test crytin.LeakTest) {
+	test.N, test.Rand = 20000, crytin.NewSeededReader(32)
+	r, err := crytin.DetectTimingLeak(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%-24s t = %7.2f  (%.0f ns vs %.0f ns) leaky: %v", name, r.T, r.MeanA, r.MeanB, r.Leaky)
+	if r.Leaky == leaky {
+		return
+	}
+	if timingVerdicts {
+		t.Errorf("%s: leaky %v, expected %v", name, r.Leaky, leaky)
+	} else {
+		t.Logf("%s: leaky %v, expected %v (not checked)", name, r.Leaky, leaky)
+	}
+}
+
+func randomBytes(n int) func() []byte {
+	rnd := crytin.NewSeededReader(320)
+	return func() []byte {
+		b, _ := crytin.RandomBytes(rnd, n)
+		return b
+	}
+}
+
+func fixedBytes(b []byte) func() []byte {
+	return func() []byte { return append([]byte{}, b...) }
+}
+
+func TestTimingLeakCompare(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing measurements in -short mode")
+	}
+	secret := randomBytes(1024)()
+	equal, random := fixedBytes(secret), randomBytes(len(secret))
+
+	leakTest(t, "InsecureCompare", true, crytin.LeakTest{Run: func(in []byte) { crytin.InsecureCompare(secret, in, 0) }, A: equal, B: random})
+	leakTest(t, "bytes.Equal", true, crytin.LeakTest{Run: func(in []byte) { bytes.Equal(secret, in) }, A: equal, B: random})
+	leakTest(t, "ConstantTimeEqual", false, crytin.LeakTest{Run: func(in []byte) { crytin.ConstantTimeEqual(secret, in) }, A: equal, B: random})
+}
+
+func TestTimingLeakPadding(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing measurements in -short mode")
+	}
+	// a valid full block of padding against random (mostly invalid) blocks
+	valid, random := fixedBytes(bytes.Repeat([]byte{16}, 16)), randomBytes(16)
+	stripped := fixedBytes(bytes.Repeat([]byte{15}, 16))
+
+	leakTest(t, "PKCS7Unpad", true, crytin.LeakTest{Run: func(in []byte) { crytin.PKCS7Unpad(&in, 16) }, A: valid, B: random})
+	// RemovePadding only branches on the last byte, more calls per
+	// measurement to see it
+	leakTest(t, "RemovePadding", true, crytin.LeakTest{Run: func(in []byte) { crytin.RemovePadding(&in) }, A: stripped, B: random, Repeat: 64})
+	leakTest(t, "ConstantTimePKCS7Unpad", false, crytin.LeakTest{Run: func(in []byte) { crytin.ConstantTimePKCS7Unpad(&in, 16) }, A: valid, B: random})
+}
+
+func TestTimingLeakLookup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing measurements in -short mode")
+	}
+	table := randomBytes(4096)()
+	zero, random := fixedBytes([]byte{0, 0}), randomBytes(2)
+	index := func(in []byte) int { return (int(in[0])<<8 | int(in[1])) % len(table) }
+
+	leakTest(t, "ConstantTimeLookup", false, crytin.LeakTest{Run: func(in []byte) { crytin.ConstantTimeLookup(table, index(in)) }, A: zero, B: random})
+}
+
+func TestConstantTimeHelpers(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{{"abc", "abc", true}, {"abc", "abd", false}, {"abc", "ab", false}, {"", "", true}} {
+		if got := crytin.ConstantTimeEqual([]byte(tc.a), []byte(tc.b)); got != tc.want {
+			t.Errorf("ConstantTimeEqual(%q, %q) = %v", tc.a, tc.b, got)
+		}
+	}
+
+	// same answers as PKCS7Unpad
+	for _, s := range []string{"ICE ICE BABY\x04\x04\x04\x04", "ICE ICE BABY\x05\x05\x05\x05", "ICE ICE BABY\x01\x02\x03\x04",
+		"ICE ICE BABY ICE", "ICE ICE BABY\x00\x00\x00\x00", "ICE ICE BABY\x04\x04\x04\x11", "0123456789abcde", ""} {
+		want, got := []byte(s), []byte(s)
+		wantErr := crytin.PKCS7Unpad(&want, 16)
+		gotErr := crytin.ConstantTimePKCS7Unpad(&got, 16)
+		if gotErr != wantErr || !bytes.Equal(got, want) {
+			t.Errorf("%q: got %q, %v want %q, %v", s, got, gotErr, want, wantErr)
+		}
+	}
+
+	table := []byte("lookup table")
+	for i := range table {
+		if got := crytin.ConstantTimeLookup(table, i); got != table[i] {
+			t.Errorf("ConstantTimeLookup(%d) = %q want %q", i, got, table[i])
+		}
+	}
+}
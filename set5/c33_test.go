@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+	"github.com/srinivengala/cryptopals/crytin/dh"
+)
+
+//Implement Diffie-Hellman
+//
+// Set a variable "p" to 37 and "g" to 5.
+// Generate "a", a random number mod 37. Now generate "A", which is "g" raised to the "a" power mode 37 --- A = (g**a) % p.
+// Do the same for "b" and "B". "A" and "B" are public keys. Generate a session key with them; set "s" to "B" raised to the "a" power mod 37 --- s = (B**a) % p.
+// Do the same with A**b, check that you come up with the same "s".
+// To turn "s" into a key, you can just hash it to create 128 bits of key material (or SHA256 it to create a key for encrypting and a key for a MAC).
+// Ok, that was fun, now repeat the exercise with bignums like in the real world. Here are parameters NIST likes:
+//   p: ffffffffffffffffc90fdaa22168c234c4c6628b80dc1cd1 ... fffffffffffff   g: 2
+
+// go test
+// go test -v
+
+func TestDHToy(t *testing.T) {
+	rnd := crytin.NewSeededReader(33)
+	for i := 0; i < 50; i++ {
+		a, err := dh.GenerateKey(dh.Toy, rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := dh.GenerateKey(dh.Toy, rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Private.Sign() <= 0 || a.Private.Cmp(big.NewInt(36)) >= 0 {
+			t.Fatalf("private key %v out of range", a.Private)
+		}
+		s1, s2 := a.Shared(b.Public), b.Shared(a.Public)
+		if s1.Cmp(s2) != 0 {
+			t.Fatalf("a=%v b=%v: %v != %v", a.Private, b.Private, s1, s2)
+		}
+		// (g**a)**b by hand
+		if want := new(big.Int).Exp(big.NewInt(5), new(big.Int).Mul(a.Private, b.Private), big.NewInt(37)); s1.Cmp(want) != 0 {
+			t.Fatalf("s = %v want %v", s1, want)
+		}
+	}
+}
+
+func TestMODPGroups(t *testing.T) {
+	for _, bits := range dh.MODPSizes {
+		if testing.Short() && bits > 2048 {
+			continue
+		}
+		g, err := dh.MODP(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hex := g.P.Text(16)
+		if g.P.BitLen() != bits || !strings.HasPrefix(hex, "ffffffffffffffffc90fdaa22168c234c4c6628b80dc1cd1") || !strings.HasSuffix(hex, "ffffffffffffffff") {
+			t.Errorf("%s: unexpected p %s...%s", g.Name, hex[:48], hex[len(hex)-16:])
+		}
+		// safe prime: p and (p-1)/2 prime
+		q := new(big.Int).Rsh(g.P, 1)
+		if !g.P.ProbablyPrime(0) || !q.ProbablyPrime(0) {
+			t.Errorf("%s: not a safe prime", g.Name)
+		}
+	}
+	if _, err := dh.MODP(1000); !errors.Is(err, dh.ErrGroup) {
+		t.Errorf("expected ErrGroup, got %v", err)
+	}
+}
+
+func TestDHNIST(t *testing.T) {
+	g, err := dh.MODP(1536)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := dh.GenerateKey(g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := dh.GenerateKey(g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dh.CheckPublic(g, a.Public); err != nil {
+		t.Fatal(err)
+	}
+
+	sa, sb := a.Shared(b.Public), b.Shared(a.Public)
+	for _, kdf := range []dh.KDF{dh.SHA1KDF, dh.SHA256KDF} {
+		ka, kb := kdf(sa), kdf(sb)
+		if len(ka) != crytin.KeySize || !bytes.Equal(ka, kb) {
+			t.Errorf("keys differ: %x %x", ka, kb)
+		}
+	}
+	if bytes.Equal(dh.SHA1KDF(sa), dh.SHA256KDF(sa)) {
+		t.Error("SHA-1 and SHA-256 keys are the same")
+	}
+}
+
+func TestDHReplay(t *testing.T) {
+	g, err := dh.MODP(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1, _ := dh.GenerateKey(g, crytin.NewSeededReader(330))
+	a2, _ := dh.GenerateKey(g, crytin.NewSeededReader(330))
+	if a1.Private.Cmp(a2.Private) != 0 {
+		t.Error("seeded key generation differs")
+	}
+}
+
+func TestDHCheckPublic(t *testing.T) {
+	g, err := dh.MODP(1536)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm1 := new(big.Int).Sub(g.P, big.NewInt(1))
+	for _, y := range []*big.Int{big.NewInt(0), big.NewInt(1), pm1, g.P} {
+		if err := dh.CheckPublic(g, y); err != dh.ErrPublicKey {
+			t.Errorf("public key %x accepted", y)
+		}
+	}
+	if err := dh.CheckPublic(g, big.NewInt(2)); err != nil {
+		t.Error(err)
+	}
+}
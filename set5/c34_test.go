@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+	"github.com/srinivengala/cryptopals/crytin/dh"
+	"github.com/srinivengala/cryptopals/crytin/netsim"
+)
+
+//Implement a MITM key-fixing attack on Diffie-Hellman with parameter injection
+//
+// A->M Send "p", "g", "A"
+// M->B Send "p", "g", "p"
+// B->M Send "B"
+// M->A Send "p"
+// A->M Send AES-CBC(SHA1(s)[0:16], iv=random(16), msg) + iv
+// M->B Relay that to B
+// B->M Send AES-CBC(SHA1(s)[0:16], iv=random(16), A's msg) + iv
+// M->A Relay that to A
+//
+// M should be able to decrypt the messages. "A" and "B" in the protocol --- the public keys, over the wire --- have been swapped out with "p".
+
+// go test
+// go test -v
+
+var echoMsgs = [][]byte{
+	[]byte("Yellow submarine"),
+	[]byte("we all live in a"),
+	[]byte("ICE ICE BABY"),
+}
+
+func TestDHEcho(t *testing.T) {
+	g, err := dh.MODP(1536)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, negotiate := range []bool{false, true} {
+		net := netsim.New(nil)
+		a, b := net.Join("A"), net.Join("B")
+		client := &dh.Echo{Group: g, Negotiate: negotiate}
+		server := &dh.Echo{Negotiate: negotiate}
+		err := netsim.Run(
+			func() error { return client.Client(a, "B", echoMsgs) },
+			func() error { return server.Server(b, "A", len(echoMsgs)) },
+		)
+		if err != nil {
+			t.Fatalf("negotiate %v: %v", negotiate, err)
+		}
+
+		// an eavesdropper sees cipher text only
+		for _, m := range net.Delivered() {
+			if sealed, ok := m.Payload.([]byte); ok {
+				for _, msg := range echoMsgs {
+					if bytes.Contains(sealed, msg) {
+						t.Errorf("plain text on the wire: %v", m)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMITMParameterInjection(t *testing.T) {
+	g, err := dh.MODP(1536)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plains, err := dh.RunMITM(dh.ParameterInjection, g, echoMsgs, crytin.NewSeededReader(34))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMITMTranscript(t, plains)
+
+	// the fix: p is not a valid public key
+	if dh.CheckPublic(g, g.P) == nil {
+		t.Error("CheckPublic accepted p")
+	}
+}
+
+// checkMITMTranscript : every message and its echo, decrypted by M
+func checkMITMTranscript(t *testing.T, plains []dh.Plaintext) {
+	t.Helper()
+	if len(plains) != 2*len(echoMsgs) {
+		t.Fatalf("decrypted %d messages, want %d", len(plains), 2*len(echoMsgs))
+	}
+	for i, p := range plains {
+		from, to := "A", "B"
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		if p.From != from || p.To != to || !bytes.Equal(p.Text, echoMsgs[i/2]) {
+			t.Errorf("message %d: %s -> %s %q", i, p.From, p.To, p.Text)
+		}
+	}
+}
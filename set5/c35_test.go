@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/srinivengala/cryptopals/crytin"
+	"github.com/srinivengala/cryptopals/crytin/dh"
+	"github.com/srinivengala/cryptopals/crytin/netsim"
+)
+
+//Implement DH with negotiated groups, and break with malicious "g" parameters
+//
+// A->B Send "p", "g"
+// B->A Send ACK
+// A->B Send "A"
+// B->A Send "B"
+// A->B Send AES-CBC(SHA1(s)[0:16], iv=random(16), msg) + iv
+// B->A Send AES-CBC(SHA1(s)[0:16], iv=random(16), A's msg) + iv
+//
+// Do the MITM attack again, but play with "g". What happens with:
+//   g = 1
+//   g = p
+//   g = p - 1
+// Write attacks for each.
+
+// go test
+// go test -v
+
+func TestMITMMaliciousG(t *testing.T) {
+	modp, err := dh.MODP(1536)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, attack := range []dh.Attack{dh.MaliciousG1, dh.MaliciousGP, dh.MaliciousGPMinus1} {
+		for _, g := range []*dh.Group{dh.Toy, modp} {
+			// several seeds: with g = p-1 the parity of a and b decides the secrets
+			for seed := uint32(0); seed < 8; seed++ {
+				plains, err := dh.RunMITM(attack, g, echoMsgs, crytin.NewSeededReader(350+seed))
+				if err != nil {
+					t.Fatalf("%v, %s, seed %d: %v", attack, g.Name, seed, err)
+				}
+				checkMITMTranscript(t, plains)
+			}
+			t.Logf("%v on %s: decrypted", attack, g.Name)
+		}
+	}
+}
+
+func TestMITMWrongProtocol(t *testing.T) {
+	// malicious g needs the negotiated protocol: M lets the messages pass
+	// and reports it could not attack
+	mitm := dh.NewMITM(dh.MaliciousG1, nil)
+	net := netsim.New(mitm)
+	a, b := net.Join("A"), net.Join("B")
+	client := &dh.Echo{Group: dh.Toy}
+	server := &dh.Echo{}
+	err := netsim.Run(
+		func() error { return client.Client(a, "B", echoMsgs) },
+		func() error { return server.Server(b, "A", len(echoMsgs)) },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mitm.Transcript(); !errors.Is(err, dh.ErrPayload) {
+		t.Errorf("expected ErrPayload, got %v", err)
+	}
+}
+
+func TestMITMMaliciousGPMinus1Seeds(t *testing.T) {
+	// A's key is never guessed from the padding, so no seed trips M up
+	// (seed 6294 did when M picked the first key with valid padding)
+	for seed := uint32(0); seed < 4096; seed++ {
+		plains, err := dh.RunMITM(dh.MaliciousGPMinus1, dh.Toy, echoMsgs[:1], crytin.NewSeededReader(3500+seed))
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if len(plains) != 2 || string(plains[0].Text) != string(echoMsgs[0]) || string(plains[1].Text) != string(echoMsgs[0]) {
+			t.Fatalf("seed %d: %q", seed, plains)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/srinivengala/cryptopals/crytin/netsim"
+)
+
+//In-process network (crytin/netsim)
+//
+// Passing, rewriting, dropping and replaying messages: the moves the MITM
+// attacks of challenges 34 and 35 are made of.
+
+// go test
+// go test -v
+
+// echoParties : A sends msg to B and expects it back
+func echoParties(net *netsim.Network, msg []byte) (a, b func() error, echoed *[]byte) {
+	ea, eb := net.Join("A"), net.Join("B")
+	echoed = new([]byte)
+	a = func() error {
+		if err := ea.Send("B", "msg", msg); err != nil {
+			return err
+		}
+		m, err := ea.Expect("echo")
+		if err != nil {
+			return err
+		}
+		*echoed = m.Payload.([]byte)
+		return nil
+	}
+	b = func() error {
+		m, err := eb.Expect("msg")
+		if err != nil {
+			return err
+		}
+		return eb.Send("A", "echo", m.Payload)
+	}
+	return a, b, echoed
+}
+
+func TestNetsimPassive(t *testing.T) {
+	net := netsim.New(nil)
+	a, b, echoed := echoParties(net, []byte("hello"))
+	if err := netsim.Run(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(*echoed) != "hello" {
+		t.Errorf("echo %q", *echoed)
+	}
+
+	tr := net.Transcript()
+	if len(tr) != 2 || tr[0].Sent.Type != "msg" || tr[1].Sent.Type != "echo" || tr[1].Sent.From != "B" {
+		t.Fatalf("transcript %v", tr)
+	}
+	for _, r := range tr {
+		t.Log(r.Sent)
+		if len(r.Delivered) != 1 || r.Delivered[0].To != r.Sent.To {
+			t.Errorf("passive network changed %v into %v", r.Sent, r.Delivered)
+		}
+	}
+}
+
+func TestNetsimRewrite(t *testing.T) {
+	// copy before changing, the payload is shared with the sender
+	net := netsim.New(netsim.AdversaryFunc(func(m netsim.Message) []netsim.Message {
+		if m.Type == "msg" {
+			m.Payload = bytes.ToUpper(m.Payload.([]byte))
+		}
+		return []netsim.Message{m}
+	}))
+	msg := []byte("hello")
+	a, b, echoed := echoParties(net, msg)
+	if err := netsim.Run(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(*echoed) != "HELLO" || string(msg) != "hello" {
+		t.Errorf("echo %q, sent %q", *echoed, msg)
+	}
+	if tr := net.Transcript(); string(tr[0].Sent.Payload.([]byte)) != "hello" || string(tr[0].Delivered[0].Payload.([]byte)) != "HELLO" {
+		t.Errorf("transcript %v", tr)
+	}
+}
+
+func TestNetsimDrop(t *testing.T) {
+	net := netsim.New(netsim.AdversaryFunc(func(m netsim.Message) []netsim.Message {
+		if m.Type == "echo" {
+			return nil
+		}
+		return []netsim.Message{m}
+	}))
+	net.Timeout = 50 * time.Millisecond
+	a, b, _ := echoParties(net, []byte("hello"))
+	if err := netsim.Run(a, b); !errors.Is(err, netsim.ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+	if tr := net.Transcript(); len(tr) != 2 || tr[1].Delivered != nil {
+		t.Errorf("transcript %v", tr)
+	}
+}
+
+func TestNetsimReplay(t *testing.T) {
+	// every message is delivered twice
+	net := netsim.New(netsim.AdversaryFunc(func(m netsim.Message) []netsim.Message {
+		return []netsim.Message{m, m}
+	}))
+	net.Timeout = 50 * time.Millisecond
+	ea, eb := net.Join("A"), net.Join("B")
+	if err := ea.Send("B", "msg", "pay me"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		m, err := eb.Expect("msg")
+		if err != nil || m.Payload != "pay me" {
+			t.Fatalf("delivery %d: %v %v", i, m, err)
+		}
+	}
+	if len(net.Delivered()) != 2 {
+		t.Errorf("delivered %v", net.Delivered())
+	}
+
+	if _, err := eb.Expect("other"); !errors.Is(err, netsim.ErrTimeout) {
+		t.Error(err)
+	}
+	ea.Send("B", "msg", "again")
+	if _, err := eb.Expect("other"); !errors.Is(err, netsim.ErrUnexpected) {
+		t.Errorf("expected ErrUnexpected, got %v", err)
+	}
+	if err := ea.Send("C", "msg", nil); !errors.Is(err, netsim.ErrUnknownParty) {
+		t.Errorf("expected ErrUnknownParty, got %v", err)
+	}
+}
+
+func TestNetsimFlood(t *testing.T) {
+	// every message turns into more than an inbox holds, nobody reads them
+	net := netsim.New(netsim.AdversaryFunc(func(m netsim.Message) []netsim.Message {
+		out := make([]netsim.Message, 100)
+		for i := range out {
+			out[i] = m
+		}
+		return out
+	}))
+	net.Timeout = 50 * time.Millisecond
+	ea, _ := net.Join("A"), net.Join("B")
+	if err := ea.Send("B", "msg", "flood"); !errors.Is(err, netsim.ErrInboxFull) {
+		t.Errorf("expected ErrInboxFull, got %v", err)
+	}
+}
+
+func TestNetsimAdversaryTranscript(t *testing.T) {
+	// an adversary replaying the first message it saw, read from the transcript
+	var net *netsim.Network
+	net = netsim.New(netsim.AdversaryFunc(func(m netsim.Message) []netsim.Message {
+		if tr := net.Transcript(); len(tr) > 0 {
+			return []netsim.Message{tr[0].Sent}
+		}
+		return []netsim.Message{m}
+	}))
+	net.Timeout = 50 * time.Millisecond
+	ea, eb := net.Join("A"), net.Join("B")
+	for _, p := range []string{"first", "second"} {
+		if err := ea.Send("B", "msg", p); err != nil {
+			t.Fatal(err)
+		}
+		if m, err := eb.Expect("msg"); err != nil || m.Payload != "first" {
+			t.Errorf("got %v %v", m, err)
+		}
+	}
+}
+
+func TestNetsimConcurrentOrder(t *testing.T) {
+	// the adversary's order, the transcript and the receiver must agree
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4)) // interleave on one CPU too
+	var seen []netsim.Message
+	net := netsim.New(netsim.AdversaryFunc(func(m netsim.Message) []netsim.Message {
+		seen = append(seen, m) // Intercept calls are serialized
+		return []netsim.Message{m}
+	}))
+	const senders, each = 16, 100
+	er := net.Join("R")
+	var got []netsim.Message
+	parties := []func() error{func() error {
+		for i := 0; i < senders*each; i++ {
+			m, err := er.Recv()
+			if err != nil {
+				return err
+			}
+			got = append(got, m)
+		}
+		return nil
+	}}
+	for s := 0; s < senders; s++ {
+		e := net.Join(string(rune('A' + s)))
+		parties = append(parties, func() error {
+			for i := 0; i < each; i++ {
+				if err := e.Send("R", "msg", i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := netsim.Run(parties...); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, delivered := net.Transcript(), net.Delivered()
+	if len(tr) != len(seen) || len(delivered) != len(seen) || len(got) != len(seen) {
+		t.Fatalf("lengths: seen %d, transcript %d, delivered %d, received %d", len(seen), len(tr), len(delivered), len(got))
+	}
+	for i, m := range seen {
+		if tr[i].Sent != m || delivered[i] != m || got[i] != m {
+			t.Fatalf("message %d out of order: seen %v, transcript %v, delivered %v, received %v", i, m, tr[i].Sent, delivered[i], got[i])
+		}
+	}
+}